@@ -0,0 +1,63 @@
+// Command fakenm runs a scripted NetworkManager on a private D-Bus daemon so
+// wifi-connect can be tested end-to-end on machines without a radio.
+// See docs/fake-networkmanager.md for usage.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+func main() {
+	var scenario, record string
+	flag.StringVar(&scenario, "scenario", "", "JSON file describing the devices, access points and scripted results of the fake")
+	flag.StringVar(&record, "record", "", "File receiving the settings of every AddAndActivateConnection call as JSON lines")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.TextFormatter{
+		DisableSorting: true,
+	})
+	logger.SetLevel(logrus.InfoLevel)
+
+	nm := fake.NewNetworkManager()
+	if scenario != "" {
+		var err error
+		nm, err = fake.LoadScenario(scenario)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// connects to $DBUS_SYSTEM_BUS_ADDRESS when set
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		panic(err)
+	}
+
+	svc := fake.NewService(logger, nm)
+	if record != "" {
+		f, err := os.OpenFile(record, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		svc.Record = f
+	}
+	err = svc.Start(conn)
+	if err != nil {
+		panic(err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	logger.Info(fmt.Sprintf("Stop signal received, shutting down fake NetworkManager (%v) ...", sig))
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
@@ -11,10 +13,16 @@ import (
 	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/command"
 	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/httpserver"
 	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/network"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/portalserver"
 )
 
 func main() {
 	cfg := models.NewConfig()
+	err := cfg.Validate()
+	if err != nil {
+		panic(err)
+	}
 	logger := logrus.New()
 
 	logger.SetFormatter(&logrus.TextFormatter{
@@ -25,12 +33,33 @@ func main() {
 	// ---------------------------Command -----------------------------
 	cmd := command.NewCommand(logger, cfg)
 
+	// --------------------------- Portal DHCP and DNS server ----------
+	ps := portalserver.NewPortalServer(logger, cfg)
+
 	// --------------------------- Go Network Manager ------------------
-	nw, err := network.NewNetwork(logger, cmd, cfg)
+	nm, err := networkmanager.NewNetworkManager()
+	if err != nil {
+		panic(err)
+	}
+
+	nw, err := network.NewNetwork(logger, cmd, ps, nm, cfg)
 	if err != nil {
 		panic(err)
 	}
 
+	if cfg.Scan {
+		result, err := nw.Scan(context.Background())
+		if err != nil {
+			panic(err)
+		}
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(out))
+		return
+	}
+
 	// --------------------------- HTTP Server ------------------------
 	httpServer := httpserver.NewHTTPServer(logger, nw, cfg)
 	nw.HTTPServer = httpServer
@@ -1,7 +1,11 @@
 package interfaces
 
+import "github.com/umeshlumbhani/go-wifi-connect/internal/models"
+
 // Command represents comand
 type Command interface {
-	StartDnsmasq(dInt string)
+	StartDnsmasq(dInt string, subnet models.PortalSubnet)
 	KillDNSMasq()
+	GetProcessStatus() (status []models.ProcessStatus)
+	GetDnsmasqLeases() (leases []models.Lease, err error)
 }
@@ -1,11 +1,22 @@
 package interfaces
 
-import "github.com/umeshlumbhani/go-wifi-connect/internal/models"
+import (
+	"context"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
 
 // Network represents network module
 type Network interface {
 	GetAccessPoint() (accessPoints []models.AccessPoint, err error)
+	Scan(ctx context.Context) (result models.ScanResult, err error)
 	CreateHotSpot() (err error)
 	CloseHotSpot() (err error)
-	Connect(ssid string, pwd string, identity string) (flg bool)
+	Connect(req models.ConnectRequest) (err error)
+	GetSavedNetworks() (networks []models.SavedNetwork, err error)
+	ForgetSavedNetwork(id string) (err error)
+	SetSavedNetworkPriority(id string, priority int32) (err error)
+	GetHotSpot() (hotspot models.HotSpot, err error)
+	GetProcessStatus() (status []models.ProcessStatus)
+	GetPortalClients() (clients []models.Lease, err error)
 }
@@ -0,0 +1,75 @@
+package interfaces
+
+import (
+	"context"
+	"net"
+
+	"github.com/Wifx/gonetworkmanager"
+)
+
+// NetworkManager represents the NetworkManager calls used by the network module
+type NetworkManager interface {
+	GetWifiDevices() (devices []WifiDevice, err error)
+	GetIP4Networks() (networks []*net.IPNet, err error)
+	ListConnections() (conns []Connection, err error)
+	AddAndActivateConnection(connection map[string]map[string]interface{}, d WifiDevice) (ac ActiveConnection, err error)
+	AddAndActivateWirelessConnection(connection map[string]map[string]interface{}, d WifiDevice, ap AccessPoint) (ac ActiveConnection, err error)
+	DeactivateConnection(ac ActiveConnection) (err error)
+	GetPropertyConnectivity() (connectivity gonetworkmanager.NmConnectivity, err error)
+	SubscribeConnectivity(ctx context.Context) (changes <-chan gonetworkmanager.NmConnectivity, err error)
+}
+
+// WifiDevice represents a wireless device known to NetworkManager
+type WifiDevice interface {
+	GetPropertyInterface() (string, error)
+	GetPropertyState() (gonetworkmanager.NmDeviceState, error)
+	GetPropertyHwAddress() (string, error)
+	GetPropertyWirelessCapabilities() (uint32, error)
+	GetAccessPoints() ([]AccessPoint, error)
+	RequestScan() error
+	GetPropertyLastScan() (int64, error)
+	GetPropertyAvailableConnections() ([]Connection, error)
+	SubscribeState(ctx context.Context) (changes <-chan DeviceStateChange, err error)
+}
+
+// AccessPoint represents an access point visible to a wireless device
+type AccessPoint interface {
+	GetPropertySSID() (string, error)
+	GetPropertyFlags() (uint32, error)
+	GetPropertyWPAFlags() (uint32, error)
+	GetPropertyRSNFlags() (uint32, error)
+	GetPropertyStrength() (uint8, error)
+	GetPropertyFrequency() (uint32, error)
+	GetPropertyMaxBitrate() (uint32, error)
+	GetPropertyHWAddress() (string, error)
+}
+
+// ActiveConnection represents an activated (or activating) connection
+type ActiveConnection interface {
+	GetPropertyState() (gonetworkmanager.NmActiveConnectionState, error)
+	GetPropertyConnection() (Connection, error)
+	SubscribeState(ctx context.Context) (changes <-chan ActiveConnectionStateChange, err error)
+}
+
+// Connection represents a connection profile stored by NetworkManager
+type Connection interface {
+	GetSettings() (gonetworkmanager.ConnectionSettings, error)
+	GetSecrets(settingName string) (gonetworkmanager.ConnectionSettings, error)
+	Update(settings gonetworkmanager.ConnectionSettings) error
+	Delete() error
+}
+
+// DeviceStateChange is sent on every StateChanged signal of a device.
+// Subscription channels are closed once their context is done.
+type DeviceStateChange struct {
+	State    gonetworkmanager.NmDeviceState
+	OldState gonetworkmanager.NmDeviceState
+	Reason   gonetworkmanager.NmDeviceStateReason
+}
+
+// ActiveConnectionStateChange is sent on every StateChanged signal of an active connection.
+// Subscription channels are closed once their context is done.
+type ActiveConnectionStateChange struct {
+	State  gonetworkmanager.NmActiveConnectionState
+	Reason gonetworkmanager.NmActiveConnectionStateReason
+}
@@ -0,0 +1,10 @@
+package interfaces
+
+import "github.com/umeshlumbhani/go-wifi-connect/internal/models"
+
+// PortalServer represents the built-in DHCP and DNS server of the portal
+type PortalServer interface {
+	Start(dInt string, subnet models.PortalSubnet) (err error)
+	Stop()
+	GetLeases() (leases []models.Lease)
+}
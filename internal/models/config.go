@@ -3,6 +3,11 @@ package models
 import (
 	"flag"
 	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 type ConfigHandler interface {
@@ -16,47 +21,296 @@ const (
 	defaultActivityTimeout int    = 0
 	defaultUIDirectory     string = "ui"
 	defaultListeningPort   string = "80"
+	defaultScanTTL         int    = 120
+	defaultIPv6Prefix      string = "fd42:42:42:42::/64"
+	defaultSubnetPool      string = "192.168.42.0/24,10.42.0.0/16,172.29.0.0/16"
+	defaultBand            string = HotspotBandBG
+	channelAuto            string = "auto"
+	// DHCPServerDnsmasq and DHCPServerBuiltin select the DHCP and DNS server of the portal
+	DHCPServerDnsmasq string = "dnsmasq"
+	DHCPServerBuiltin string = "builtin"
+	// PassphraseRandom is the portal passphrase generating a random one when the portal is created
+	PassphraseRandom string = "random"
 )
 
+// SSIDPlaceholders are the placeholders expanded in the portal SSID
+var SSIDPlaceholders = []string{"{hostname}", "{mac4}", "{serial}", "{machine-id}"}
+
+// MaxSSIDLength is the length limit of an SSID in bytes
+const MaxSSIDLength = 32
+
+// ssidPlaceholderPattern matches anything looking like a placeholder of the portal SSID
+var ssidPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// wpaPassphrasePattern matches a WPA passphrase, 8 to 63 printable ASCII characters, or a raw 64 hex digit key
+var wpaPassphrasePattern = regexp.MustCompile(`^([\x20-\x7e]{8,63}|[0-9a-fA-F]{64})$`)
+
+// ulaNetwork holds the unique local IPv6 addresses
+var ulaNetwork = &net.IPNet{IP: net.ParseIP("fc00::"), Mask: net.CIDRMask(7, 128)}
+
 type Config struct {
-	Gateway         string
-	Port            string
-	DHCPRange       string
-	SSID            string
-	Interface       string
-	Passphrase      string
-	UIDirectory     string
-	ActivityTimeout int
+	Gateway          string
+	Port             string
+	DHCPRange        string
+	DHCPServer       string
+	SubnetPool       string
+	IPv6             bool
+	IPv6Prefix       string
+	SSID             string
+	Band             string
+	Channel          string
+	Interface        string
+	StationInterface string
+	Passphrase       string
+	PassphraseFile   string
+	QRCodeFile       string
+	RotatePassphrase bool
+	Security         string
+	UIDirectory      string
+	ActivityTimeout  int
+	ScanTTL          int
+	Scan             bool
 }
 
 // SetConfig used to set configuration from cli argument
 func NewConfig() *Config {
-	var winterface, gateway, dhcprange, ssid, uidir, port, pwd string
-	var at int
+	var winterface, stationInterface, gateway, dhcprange, ssid, uidir, port, pwd string
+	var at, scanTTL int
+	var scan, ipv6, rotatePassphrase bool
+	var ipv6Prefix, subnetPool, band, channel, security, passphraseFile, qrCodeFile, dhcpServer string
 
-	flag.StringVar(&winterface, "portal-interface", "", "Wireless network interface to be used by WiFi Connect")
-	flag.StringVar(&ssid, "portal-ssid", defaultSSID, fmt.Sprintf("SSID of the captive portal WiFi network (default: %s)", defaultSSID))
-	flag.StringVar(&pwd, "portal-passphrase", "", "WPA2 Passphrase of the captive portal WiFi network (default: none)")
-	flag.StringVar(&gateway, "portal-gateway", defaultGateway, fmt.Sprintf("Gateway of the captive portal WiFi network (default: %s)", defaultGateway))
+	flag.StringVar(&winterface, "portal-interface", "", "Wireless network interface (name or MAC address) to be used by WiFi Connect")
+	flag.StringVar(&stationInterface, "station-interface", "", "Wireless network interface (name or MAC address) joining the configured network while the portal stays up on the portal interface (default: none)")
+	flag.StringVar(&ssid, "portal-ssid", defaultSSID, fmt.Sprintf("SSID of the captive portal WiFi network, may contain %s (default: %s)", strings.Join(SSIDPlaceholders, ", "), defaultSSID))
+	flag.StringVar(&band, "portal-band", defaultBand, fmt.Sprintf("Band of the captive portal WiFi network: %s (2.4 GHz), %s (5 GHz) or %s (5 GHz when the device supports it) (default: %s)", HotspotBandBG, HotspotBandA, HotspotBandAuto, defaultBand))
+	flag.StringVar(&channel, "portal-channel", "", fmt.Sprintf("Channel of the captive portal WiFi network, or %q for the least congested one (default: chosen by NetworkManager)", channelAuto))
+	flag.StringVar(&pwd, "portal-passphrase", "", fmt.Sprintf("Passphrase of the captive portal WiFi network, or %q to generate one (default: none)", PassphraseRandom))
+	flag.BoolVar(&rotatePassphrase, "portal-passphrase-rotate", false, fmt.Sprintf("Generate a new %s passphrase whenever the portal is opened again (default: false)", PassphraseRandom))
+	flag.StringVar(&passphraseFile, "portal-passphrase-file", "", "File the portal passphrase is written to, readable by its owner only (default: none)")
+	flag.StringVar(&qrCodeFile, "portal-qrcode-file", "", "File the QR code for joining the portal is written to when the portal is opened, PNG or SVG after its extension (default: none)")
+	flag.StringVar(&security, "portal-security", "", fmt.Sprintf("Security of the captive portal WiFi network: %s, %s, %s, %s or %s (default: %s with a passphrase, %s without)",
+		PortalSecurityOpen, PortalSecurityWPA2, PortalSecuritySAE, PortalSecurityTransition, PortalSecurityOWE, PortalSecurityWPA2, PortalSecurityOpen))
+	flag.StringVar(&gateway, "portal-gateway", defaultGateway, fmt.Sprintf("Gateway of the captive portal WiFi network, with the prefix length of its subnet in CIDR notation or a /24, or %q to pick a free subnet from the pool (default: %s)", PortalNetworkAuto, defaultGateway))
 	flag.StringVar(&dhcprange, "portal-dhcp-range", defaultDHCPRange, fmt.Sprintf("DHCP range of the WiFi network (default: %s)", defaultDHCPRange))
+	flag.StringVar(&dhcpServer, "portal-dhcp-server", DHCPServerDnsmasq, fmt.Sprintf("DHCP and DNS server of the captive portal WiFi network: %s, or %s to serve them from WiFi Connect itself (default: %s)", DHCPServerDnsmasq, DHCPServerBuiltin, DHCPServerDnsmasq))
+	flag.StringVar(&subnetPool, "portal-subnet-pool", defaultSubnetPool, fmt.Sprintf("Networks the portal /24 is picked from when the portal gateway is %q (default: %s)", PortalNetworkAuto, defaultSubnetPool))
+	flag.BoolVar(&ipv6, "portal-ipv6", false, "Serve the captive portal over IPv6 too, with router advertisements and DHCPv6 (default: false)")
+	flag.StringVar(&ipv6Prefix, "portal-ipv6-prefix", defaultIPv6Prefix, fmt.Sprintf("Unique local /64 prefix of the captive portal WiFi network when IPv6 is enabled (default: %s)", defaultIPv6Prefix))
 	flag.StringVar(&port, "portal-listening-port", defaultListeningPort, fmt.Sprintf("Listening port of the captive portal web server (default: %s)", defaultListeningPort))
 	flag.IntVar(&at, "activity-timeout", defaultActivityTimeout, "Exit if no activity for the specified time (seconds) (default: 0)")
 	flag.StringVar(&uidir, "ui-directory", defaultUIDirectory, fmt.Sprintf("Web UI directory location (default: %s)", defaultUIDirectory))
+	flag.IntVar(&scanTTL, "scan-ttl", defaultScanTTL, fmt.Sprintf("Keep networks missing from newer scans for the specified time (seconds) (default: %d)", defaultScanTTL))
+	flag.BoolVar(&scan, "scan", false, "Scan for WiFi networks, print them as JSON and exit")
 
 	flag.Parse()
 
 	return &Config{
-		Gateway:         gateway,
-		Port:            port,
-		DHCPRange:       dhcprange,
-		SSID:            ssid,
-		Interface:       winterface,
-		Passphrase:      pwd,
-		UIDirectory:     uidir,
-		ActivityTimeout: at,
+		Gateway:          gateway,
+		Port:             port,
+		DHCPRange:        dhcprange,
+		DHCPServer:       dhcpServer,
+		SubnetPool:       subnetPool,
+		IPv6:             ipv6,
+		IPv6Prefix:       ipv6Prefix,
+		SSID:             ssid,
+		Band:             band,
+		Channel:          channel,
+		Interface:        winterface,
+		StationInterface: stationInterface,
+		Passphrase:       pwd,
+		PassphraseFile:   passphraseFile,
+		QRCodeFile:       qrCodeFile,
+		RotatePassphrase: rotatePassphrase,
+		Security:         security,
+		UIDirectory:      uidir,
+		ActivityTimeout:  at,
+		ScanTTL:          scanTTL,
+		Scan:             scan,
 	}
 }
 
 func (c *Config) Fetch() Config {
 	return *c
 }
+
+// Validate checks the portal network settings, WiFi Connect refuses to start with an unusable portal
+func (c Config) Validate() (err error) {
+	err = c.validatePortalNetwork()
+	if err != nil {
+		return
+	}
+	err = c.validatePortalSSID()
+	if err != nil {
+		return
+	}
+	err = c.validateHotspotRadio()
+	if err != nil {
+		return
+	}
+	err = c.validatePortalSecurity()
+	if err != nil {
+		return
+	}
+	err = c.validateQRCodeFile()
+	if err != nil {
+		return
+	}
+	err = c.validateDHCPServer()
+	if err != nil {
+		return
+	}
+	if c.IPv6 {
+		_, _, err = c.IPv6Gateway()
+	}
+	return
+}
+
+// IsBuiltinDHCPServer tells whether DHCP and DNS are served by WiFi Connect rather than dnsmasq
+func (c Config) IsBuiltinDHCPServer() bool {
+	return c.DHCPServer == DHCPServerBuiltin
+}
+
+// validateDHCPServer checks the DHCP server of the portal, the built-in one serves IPv4 only
+func (c Config) validateDHCPServer() (err error) {
+	switch c.DHCPServer {
+	case DHCPServerDnsmasq:
+	case DHCPServerBuiltin:
+		if c.IPv6 {
+			err = fmt.Errorf("portal IPv6 requires the %s DHCP server", DHCPServerDnsmasq)
+		}
+	default:
+		err = fmt.Errorf("invalid portal DHCP server %q, expected %s or %s", c.DHCPServer, DHCPServerDnsmasq, DHCPServerBuiltin)
+	}
+	return
+}
+
+// validatePortalSSID checks the placeholders of the portal SSID, the length of a template is only known once expanded
+func (c Config) validatePortalSSID() (err error) {
+	if c.SSID == "" {
+		return fmt.Errorf("portal SSID is empty")
+	}
+	placeholders := ssidPlaceholderPattern.FindAllString(c.SSID, -1)
+	for _, placeholder := range placeholders {
+		if !contains(SSIDPlaceholders, placeholder) {
+			return fmt.Errorf("unknown placeholder %s in portal SSID %q, expected one of %s", placeholder, c.SSID, strings.Join(SSIDPlaceholders, ", "))
+		}
+	}
+	if len(placeholders) == 0 && len(c.SSID) > MaxSSIDLength {
+		err = fmt.Errorf("portal SSID %q is longer than %d bytes", c.SSID, MaxSSIDLength)
+	}
+	return
+}
+
+// PortalSecurity returns the security mode of the portal, WPA2 when only a passphrase is given
+func (c Config) PortalSecurity() string {
+	if c.Security != "" {
+		return c.Security
+	}
+	if c.Passphrase != "" {
+		return PortalSecurityWPA2
+	}
+	return PortalSecurityOpen
+}
+
+// IsRandomPassphrase tells whether the portal passphrase is generated
+func (c Config) IsRandomPassphrase() bool {
+	return c.Passphrase == PassphraseRandom
+}
+
+// validatePortalSecurity checks the passphrase against the security mode of the portal
+func (c Config) validatePortalSecurity() (err error) {
+	if c.RotatePassphrase && !c.IsRandomPassphrase() {
+		return fmt.Errorf("portal passphrase rotation requires a %s passphrase", PassphraseRandom)
+	}
+	switch security := c.PortalSecurity(); security {
+	case PortalSecurityOpen, PortalSecurityOWE:
+		if c.Passphrase != "" {
+			err = fmt.Errorf("portal passphrase is not used with security %s", security)
+		} else if c.PassphraseFile != "" {
+			err = fmt.Errorf("portal passphrase file is not used with security %s", security)
+		}
+	case PortalSecurityWPA2, PortalSecurityTransition:
+		if !c.IsRandomPassphrase() && !wpaPassphrasePattern.MatchString(c.Passphrase) {
+			err = fmt.Errorf("portal passphrase has to be 8 to 63 printable ASCII characters or 64 hexadecimal digits with security %s", security)
+		}
+	case PortalSecuritySAE:
+		if !c.IsRandomPassphrase() && len(c.Passphrase) < 8 {
+			err = fmt.Errorf("portal passphrase has to be at least 8 characters with security %s", security)
+		}
+	default:
+		err = fmt.Errorf("invalid portal security %q, expected %s, %s, %s, %s or %s", security,
+			PortalSecurityOpen, PortalSecurityWPA2, PortalSecuritySAE, PortalSecurityTransition, PortalSecurityOWE)
+	}
+	return
+}
+
+// IsSVGQRCodeFile tells whether the QR code file is written as SVG rather than PNG
+func (c Config) IsSVGQRCodeFile() bool {
+	return strings.EqualFold(filepath.Ext(c.QRCodeFile), ".svg")
+}
+
+// validateQRCodeFile checks the QR code file has a known image extension
+func (c Config) validateQRCodeFile() (err error) {
+	if c.QRCodeFile == "" || c.IsSVGQRCodeFile() || strings.EqualFold(filepath.Ext(c.QRCodeFile), ".png") {
+		return
+	}
+	return fmt.Errorf("portal QR code file %q has to end with .png or .svg", c.QRCodeFile)
+}
+
+// IsAutoChannel tells whether the portal channel is the least congested one of the last scan
+func (c Config) IsAutoChannel() bool {
+	return c.Channel == channelAuto
+}
+
+// HotspotChannel returns the configured channel of the portal, 0 when NetworkManager chooses it or in auto mode
+func (c Config) HotspotChannel() (channel uint32, err error) {
+	if c.Channel == "" || c.IsAutoChannel() {
+		return
+	}
+	value, err := strconv.ParseUint(c.Channel, 10, 32)
+	if err != nil || ChannelBand(uint32(value)) == "" {
+		err = fmt.Errorf("invalid portal channel %q", c.Channel)
+		return
+	}
+	channel = uint32(value)
+	return
+}
+
+// validateHotspotRadio checks that the portal band and channel go together
+func (c Config) validateHotspotRadio() (err error) {
+	switch c.Band {
+	case HotspotBandBG, HotspotBandA, HotspotBandAuto:
+	default:
+		return fmt.Errorf("invalid portal band %q, expected %s, %s or %s", c.Band, HotspotBandBG, HotspotBandA, HotspotBandAuto)
+	}
+	channel, err := c.HotspotChannel()
+	if err != nil {
+		return
+	}
+	if channel != 0 && c.Band != HotspotBandAuto && ChannelBand(channel) != c.Band {
+		err = fmt.Errorf("portal channel %d is not in band %s", channel, c.Band)
+	}
+	return
+}
+
+// IPv6Gateway returns the IPv6 address of the portal, the first address of the portal prefix
+func (c Config) IPv6Gateway() (gateway net.IP, prefix int, err error) {
+	ip, subnet, err := net.ParseCIDR(c.IPv6Prefix)
+	if err != nil || ip.To4() != nil {
+		err = fmt.Errorf("invalid portal IPv6 prefix %q", c.IPv6Prefix)
+		return
+	}
+	prefix, _ = subnet.Mask.Size()
+	if prefix != 64 {
+		err = fmt.Errorf("portal IPv6 prefix %s has to be a /64, clients configure their address with SLAAC", c.IPv6Prefix)
+		return
+	}
+	if !ulaNetwork.Contains(ip) {
+		err = fmt.Errorf("portal IPv6 prefix %s is not a unique local prefix (fc00::/7)", c.IPv6Prefix)
+		return
+	}
+	gateway = make(net.IP, net.IPv6len)
+	copy(gateway, subnet.IP)
+	gateway[net.IPv6len-1] = 1
+	return
+}
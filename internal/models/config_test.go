@@ -0,0 +1,80 @@
+package models
+
+import "testing"
+
+func TestIPv6Gateway(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		wantGateway string
+		wantErr     bool
+	}{
+		{name: "default", prefix: defaultIPv6Prefix, wantGateway: "fd42:42:42:42::1"},
+		{name: "address within the prefix", prefix: "fd00:1:2:3::abcd/64", wantGateway: "fd00:1:2:3::1"},
+		{name: "fc00::/8 half", prefix: "fc00:1:2:3::/64", wantGateway: "fc00:1:2:3::1"},
+		{name: "global prefix", prefix: "2001:db8:1:2::/64", wantErr: true},
+		{name: "link-local prefix", prefix: "fe80::/64", wantErr: true},
+		{name: "/48 prefix", prefix: "fd42:42:42::/48", wantErr: true},
+		{name: "/80 prefix", prefix: "fd42:42:42:42::/80", wantErr: true},
+		{name: "ipv4 network", prefix: "192.168.42.0/24", wantErr: true},
+		{name: "address without prefix", prefix: "fd42:42:42:42::1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway, prefix, err := Config{IPv6Prefix: tt.prefix}.IPv6Gateway()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("IPv6Gateway returned %s/%d, want an error", gateway, prefix)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("IPv6Gateway: %s", err.Error())
+			}
+			if gateway.String() != tt.wantGateway || prefix != 64 {
+				t.Errorf("gateway %s/%d, want %s/64", gateway, prefix, tt.wantGateway)
+			}
+		})
+	}
+}
+
+func TestValidatePortalSecurity(t *testing.T) {
+	hexKey := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name           string
+		security       string
+		passphrase     string
+		passphraseFile string
+		rotate         bool
+		wantErr        bool
+	}{
+		{name: "open by default", security: ""},
+		{name: "wpa2 by passphrase", security: "", passphrase: "portal-secret"},
+		{name: "open", security: PortalSecurityOpen},
+		{name: "open with passphrase", security: PortalSecurityOpen, passphrase: "portal-secret", wantErr: true},
+		{name: "open with passphrase file", security: PortalSecurityOpen, passphraseFile: "/etc/wifi-connect/passphrase", wantErr: true},
+		{name: "owe", security: PortalSecurityOWE},
+		{name: "owe with passphrase", security: PortalSecurityOWE, passphrase: "portal-secret", wantErr: true},
+		{name: "wpa2 under 8 characters", security: PortalSecurityWPA2, passphrase: "secret", wantErr: true},
+		{name: "wpa2 over 63 characters", security: PortalSecurityWPA2, passphrase: hexKey + "!", wantErr: true},
+		{name: "wpa2 random", security: PortalSecurityWPA2, passphrase: PassphraseRandom},
+		{name: "wpa2 random rotated", security: PortalSecurityWPA2, passphrase: PassphraseRandom, rotate: true},
+		{name: "rotation without random", security: PortalSecurityWPA2, passphrase: "portal-secret", rotate: true, wantErr: true},
+		{name: "sae", security: PortalSecuritySAE, passphrase: "portal-secret"},
+		{name: "sae under 8 characters", security: PortalSecuritySAE, passphrase: "secret7", wantErr: true},
+		{name: "sae over 63 characters", security: PortalSecuritySAE, passphrase: hexKey + "-and-more"},
+		{name: "transition with a 64 hex key", security: PortalSecurityTransition, passphrase: hexKey},
+		{name: "transition with 64 other characters", security: PortalSecurityTransition, passphrase: hexKey[:63] + "g", wantErr: true},
+		{name: "transition without passphrase", security: PortalSecurityTransition, wantErr: true},
+		{name: "unknown security", security: "wep", passphrase: "portal-secret", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{Security: tt.security, Passphrase: tt.passphrase, PassphraseFile: tt.passphraseFile, RotatePassphrase: tt.rotate}
+			err := c.validatePortalSecurity()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePortalSecurity returned %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import "fmt"
+
+// EAP methods supported on enterprise networks
+const (
+	EAPMethodPEAP = "peap"
+	EAPMethodTTLS = "ttls"
+	EAPMethodTLS  = "tls"
+	EAPMethodPWD  = "pwd"
+)
+
+// phase2Methods lists the inner authentication methods allowed for each tunnelled EAP method
+var phase2Methods = map[string][]string{
+	EAPMethodPEAP: {"mschapv2", "gtc", "md5"},
+	EAPMethodTTLS: {"pap", "chap", "mschap", "mschapv2", "gtc", "md5"},
+}
+
+// ConnectRequest request object for connect
+type ConnectRequest struct {
+	Identity   string `json:"identity"`
+	Passphrase string `json:"passphrase"`
+	SSID       string `json:"ssid"`
+	// Hidden networks are not scanned, Security gives their security mode
+	Hidden   bool   `json:"hidden"`
+	Security string `json:"security"`
+	// EAP settings, only used on enterprise networks
+	EAPMethod          string `json:"eapMethod"`
+	Phase2Auth         string `json:"phase2Auth"`
+	AnonymousIdentity  string `json:"anonymousIdentity"`
+	DomainSuffixMatch  string `json:"domainSuffixMatch"`
+	CACert             string `json:"caCert"`
+	ClientCert         string `json:"clientCert"`
+	PrivateKey         string `json:"privateKey"`
+	PrivateKeyPassword string `json:"privateKeyPassword"`
+	// IP configuration of the joined network, DHCP and SLAAC are used when not set
+	IPv4 *IPConfig `json:"ipv4"`
+	IPv6 *IPConfig `json:"ipv6"`
+}
+
+// EAP returns the EAP method and phase-2 authentication of the request, applying defaults
+func (r ConnectRequest) EAP() (method string, phase2 string) {
+	method, phase2 = r.EAPMethod, r.Phase2Auth
+	if method == "" {
+		method = EAPMethodPEAP
+	}
+	if _, ok := phase2Methods[method]; ok && phase2 == "" {
+		phase2 = "mschapv2"
+	}
+	return
+}
+
+// ValidateEAP checks that the enterprise settings form a combination NetworkManager accepts
+func (r ConnectRequest) ValidateEAP() (err error) {
+	method, phase2 := r.EAP()
+	switch method {
+	case EAPMethodPEAP, EAPMethodTTLS:
+		if !contains(phase2Methods[method], phase2) {
+			return fmt.Errorf("phase-2 authentication %q is not supported with %s", phase2, method)
+		}
+		if r.ClientCert != "" || r.PrivateKey != "" {
+			return fmt.Errorf("client certificate is only supported with %s", EAPMethodTLS)
+		}
+		if r.Identity == "" || r.Passphrase == "" {
+			return fmt.Errorf("identity and passphrase are required with %s", method)
+		}
+	case EAPMethodTLS:
+		if r.Phase2Auth != "" {
+			return fmt.Errorf("phase-2 authentication is not supported with %s", method)
+		}
+		if r.Identity == "" {
+			return fmt.Errorf("identity is required with %s", method)
+		}
+		if r.ClientCert == "" || r.PrivateKey == "" {
+			return fmt.Errorf("client certificate and private key are required with %s", method)
+		}
+	case EAPMethodPWD:
+		if r.Phase2Auth != "" {
+			return fmt.Errorf("phase-2 authentication is not supported with %s", method)
+		}
+		if r.CACert != "" || r.ClientCert != "" || r.PrivateKey != "" || r.DomainSuffixMatch != "" {
+			return fmt.Errorf("certificates are not supported with %s", method)
+		}
+		if r.Identity == "" || r.Passphrase == "" {
+			return fmt.Errorf("identity and passphrase are required with %s", method)
+		}
+	default:
+		return fmt.Errorf("unsupported EAP method %q", method)
+	}
+	return
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package models
+
+import "testing"
+
+func TestValidateEAP(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ConnectRequest
+		wantErr bool
+	}{
+		{name: "default peap", req: ConnectRequest{Identity: "user", Passphrase: "secret"}},
+		{name: "peap mschapv2", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: EAPMethodPEAP, Phase2Auth: "mschapv2"}},
+		{name: "peap pap", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: EAPMethodPEAP, Phase2Auth: "pap"}, wantErr: true},
+		{name: "ttls pap", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: EAPMethodTTLS, Phase2Auth: "pap"}},
+		{name: "ttls mschapv2", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: EAPMethodTTLS, Phase2Auth: "mschapv2"}},
+		{name: "ttls unknown phase2", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: EAPMethodTTLS, Phase2Auth: "eap-tls"}, wantErr: true},
+		{name: "peap without passphrase", req: ConnectRequest{Identity: "user", EAPMethod: EAPMethodPEAP}, wantErr: true},
+		{name: "ttls without identity", req: ConnectRequest{Passphrase: "secret", EAPMethod: EAPMethodTTLS}, wantErr: true},
+		{
+			name:    "peap with client certificate",
+			req:     ConnectRequest{Identity: "user", Passphrase: "secret", ClientCert: "file:///client.pem", PrivateKey: "file:///client.key"},
+			wantErr: true,
+		},
+		{name: "tls", req: ConnectRequest{Identity: "device", EAPMethod: EAPMethodTLS, ClientCert: "file:///client.pem", PrivateKey: "file:///client.key"}},
+		{name: "tls without client certificate", req: ConnectRequest{Identity: "device", EAPMethod: EAPMethodTLS, PrivateKey: "file:///client.key"}, wantErr: true},
+		{name: "tls without private key", req: ConnectRequest{Identity: "device", EAPMethod: EAPMethodTLS, ClientCert: "file:///client.pem"}, wantErr: true},
+		{name: "tls without identity", req: ConnectRequest{EAPMethod: EAPMethodTLS, ClientCert: "file:///client.pem", PrivateKey: "file:///client.key"}, wantErr: true},
+		{
+			name:    "tls with phase2",
+			req:     ConnectRequest{Identity: "device", EAPMethod: EAPMethodTLS, Phase2Auth: "mschapv2", ClientCert: "file:///client.pem", PrivateKey: "file:///client.key"},
+			wantErr: true,
+		},
+		{name: "pwd", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: EAPMethodPWD}},
+		{name: "pwd with phase2", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: EAPMethodPWD, Phase2Auth: "mschapv2"}, wantErr: true},
+		{name: "pwd with ca certificate", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: EAPMethodPWD, CACert: "file:///ca.pem"}, wantErr: true},
+		{name: "unsupported method", req: ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: "leap"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.ValidateEAP()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEAP returned %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
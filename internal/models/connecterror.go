@@ -0,0 +1,67 @@
+package models
+
+import "fmt"
+
+// ConnectErrorCode identifies why a connection attempt failed, the values are stable and meant for clients
+type ConnectErrorCode string
+
+const (
+	// ConnectErrorInvalidRequest is returned when the request is incomplete or inconsistent
+	ConnectErrorInvalidRequest ConnectErrorCode = "invalid_request"
+	// ConnectErrorAuthFailed is returned when the credentials were rejected or missing
+	ConnectErrorAuthFailed ConnectErrorCode = "auth_failed"
+	// ConnectErrorDHCPFailed is returned when no IP configuration could be obtained
+	ConnectErrorDHCPFailed ConnectErrorCode = "dhcp_failed"
+	// ConnectErrorAPNotFound is returned when the network is out of range
+	ConnectErrorAPNotFound ConnectErrorCode = "ap_not_found"
+	// ConnectErrorTimeout is returned when the connection was not activated in time
+	ConnectErrorTimeout ConnectErrorCode = "timeout"
+	// ConnectErrorActivationFailed is returned when NetworkManager gave up for another reason
+	ConnectErrorActivationFailed ConnectErrorCode = "activation_failed"
+	// ConnectErrorNoConnectivity is returned when the network was joined but gives no Internet access
+	ConnectErrorNoConnectivity ConnectErrorCode = "no_connectivity"
+	// ConnectErrorInternal is returned when talking to NetworkManager failed
+	ConnectErrorInternal ConnectErrorCode = "internal_error"
+)
+
+// connectErrorMessages holds the user facing message of every code
+var connectErrorMessages = map[ConnectErrorCode]string{
+	ConnectErrorInvalidRequest:   "invalid request",
+	ConnectErrorAuthFailed:       "authentication failed, check the password or credentials",
+	ConnectErrorDHCPFailed:       "could not obtain an IP address from the network",
+	ConnectErrorAPNotFound:       "network not found, check that it is in range",
+	ConnectErrorTimeout:          "connection timed out",
+	ConnectErrorActivationFailed: "connection could not be activated",
+	ConnectErrorNoConnectivity:   "connected, but the network gives no Internet access",
+	ConnectErrorInternal:         "internal error",
+}
+
+// Message returns the user facing message of the code
+func (c ConnectErrorCode) Message() string {
+	if msg, ok := connectErrorMessages[c]; ok {
+		return msg
+	}
+	return string(c)
+}
+
+// ConnectError is returned by a failed connection attempt.
+// Detail carries the underlying cause, e.g. the NetworkManager state reason, for the logs.
+type ConnectError struct {
+	Code   ConnectErrorCode
+	Detail string
+}
+
+// NewConnectError returns a ConnectError with the given code and detail
+func NewConnectError(code ConnectErrorCode, format string, a ...interface{}) *ConnectError {
+	return &ConnectError{
+		Code:   code,
+		Detail: fmt.Sprintf(format, a...),
+	}
+}
+
+func (e *ConnectError) Error() string {
+	if e.Detail == "" {
+		return string(e.Code)
+	}
+	return fmt.Sprintf("%s - %s", e.Code, e.Detail)
+}
@@ -44,3 +44,29 @@ const (
 func (d NM80211ApSecurityFlags) U32() uint32 {
 	return uint32(d)
 }
+
+// NMWifiDeviceCapabilities describes the capabilities of a wireless device
+type NMWifiDeviceCapabilities uint32
+
+// NMWifiDeviceCapabilities
+const (
+	WifiDeviceCapNone         NMWifiDeviceCapabilities = 0x0000_0000
+	WifiDeviceCapCipherWEP40  NMWifiDeviceCapabilities = 0x0000_0001
+	WifiDeviceCapCipherWEP104 NMWifiDeviceCapabilities = 0x0000_0002
+	WifiDeviceCapCipherTKIP   NMWifiDeviceCapabilities = 0x0000_0004
+	WifiDeviceCapCipherCCMP   NMWifiDeviceCapabilities = 0x0000_0008
+	WifiDeviceCapWPA          NMWifiDeviceCapabilities = 0x0000_0010
+	WifiDeviceCapRSN          NMWifiDeviceCapabilities = 0x0000_0020
+	WifiDeviceCapAP           NMWifiDeviceCapabilities = 0x0000_0040
+	WifiDeviceCapAdhoc        NMWifiDeviceCapabilities = 0x0000_0080
+	WifiDeviceCapFreqValid    NMWifiDeviceCapabilities = 0x0000_0100
+	WifiDeviceCapFreq2GHz     NMWifiDeviceCapabilities = 0x0000_0200
+	WifiDeviceCapFreq5GHz     NMWifiDeviceCapabilities = 0x0000_0400
+	WifiDeviceCapMesh         NMWifiDeviceCapabilities = 0x0000_1000
+	WifiDeviceCapIBSSRSN      NMWifiDeviceCapabilities = 0x0000_2000
+)
+
+// U32 used to convert into uint32
+func (d NMWifiDeviceCapabilities) U32() uint32 {
+	return uint32(d)
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrHotSpotNotActive is returned when the hotspot settings are requested while the portal is closed
+var ErrHotSpotNotActive = errors.New("hotspot not active")
+
+// HotSpot defines the settings used by clients to join the portal network
+type HotSpot struct {
+	SSID       string
+	Security   string
+	Passphrase string
+}
+
+// wifiQREscaper escapes the characters with a meaning in the fields of a WIFI: URI
+var wifiQREscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+
+// WifiQRText returns the WIFI: URI of the hotspot, as read by phone cameras from a QR code.
+// Phones pick WPA2 or WPA3 from the network itself, OWE networks are joined like open ones.
+func (h HotSpot) WifiQRText() string {
+	if h.Security == PortalSecurityOpen || h.Security == PortalSecurityOWE {
+		return "WIFI:T:nopass;S:" + wifiQREscaper.Replace(h.SSID) + ";;"
+	}
+	return "WIFI:T:WPA;S:" + wifiQREscaper.Replace(h.SSID) + ";P:" + wifiQREscaper.Replace(h.Passphrase) + ";;"
+}
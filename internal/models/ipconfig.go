@@ -0,0 +1,122 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+)
+
+// IP configuration methods of a ConnectRequest
+const (
+	IPMethodAuto     = "auto"
+	IPMethodManual   = "manual"
+	IPMethodDisabled = "disabled"
+)
+
+// domainPattern matches a DNS domain name, an optional trailing dot included
+var domainPattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.?$`)
+
+// IPConfig is the IPv4 or IPv6 configuration of a ConnectRequest, the network is joined with DHCP or SLAAC without it.
+// Addresses are given in CIDR notation, DNS servers given with method auto replace the ones of the network.
+type IPConfig struct {
+	Method    string   `json:"method"`
+	Addresses []string `json:"addresses"`
+	Gateway   string   `json:"gateway"`
+	DNS       []string `json:"dns"`
+	DNSSearch []string `json:"dnsSearch"`
+}
+
+// EffectiveMethod returns the method of the configuration, auto when not set
+func (c IPConfig) EffectiveMethod() string {
+	if c.Method == "" {
+		return IPMethodAuto
+	}
+	return c.Method
+}
+
+// Validate checks the configuration for the IPv4 or IPv6 address family
+func (c IPConfig) Validate(ipv6 bool) (err error) {
+	family := "IPv4"
+	if ipv6 {
+		family = "IPv6"
+	}
+	switch method := c.EffectiveMethod(); method {
+	case IPMethodManual:
+		if len(c.Addresses) == 0 {
+			return fmt.Errorf("%s addresses are required with method %s", family, method)
+		}
+	case IPMethodAuto:
+		if len(c.Addresses) > 0 || c.Gateway != "" {
+			return fmt.Errorf("%s addresses and gateway are only supported with method %s", family, IPMethodManual)
+		}
+	case IPMethodDisabled:
+		if len(c.Addresses) > 0 || c.Gateway != "" || len(c.DNS) > 0 || len(c.DNSSearch) > 0 {
+			return fmt.Errorf("%s settings are not supported with method %s", family, method)
+		}
+	default:
+		return fmt.Errorf("unsupported %s method %q", family, c.Method)
+	}
+	var subnets []*net.IPNet
+	for _, address := range c.Addresses {
+		ip, subnet, parseErr := net.ParseCIDR(address)
+		if parseErr != nil || isIPv6(ip) != ipv6 {
+			return fmt.Errorf("invalid %s address %q, expected CIDR notation", family, address)
+		}
+		subnets = append(subnets, subnet)
+	}
+	if c.Gateway != "" {
+		gateway := net.ParseIP(c.Gateway)
+		if gateway == nil || isIPv6(gateway) != ipv6 {
+			return fmt.Errorf("invalid %s gateway %q", family, c.Gateway)
+		}
+		// IPv6 gateways are usually link-local and outside of the subnets
+		if !ipv6 && !inSubnets(subnets, gateway) {
+			return fmt.Errorf("%s gateway %s is outside of the configured subnets", family, c.Gateway)
+		}
+	}
+	for _, server := range c.DNS {
+		ip := net.ParseIP(server)
+		if ip == nil || isIPv6(ip) != ipv6 {
+			return fmt.Errorf("invalid %s DNS server %q", family, server)
+		}
+	}
+	for _, domain := range c.DNSSearch {
+		if len(domain) > 253 || !domainPattern.MatchString(domain) {
+			return fmt.Errorf("invalid DNS search domain %q", domain)
+		}
+	}
+	return
+}
+
+// ValidateIP checks the IPv4 and IPv6 configurations of the request
+func (r ConnectRequest) ValidateIP() (err error) {
+	if r.IPv4 != nil {
+		err = r.IPv4.Validate(false)
+		if err != nil {
+			return
+		}
+	}
+	if r.IPv6 != nil {
+		err = r.IPv6.Validate(true)
+		if err != nil {
+			return
+		}
+	}
+	if r.IPv4 != nil && r.IPv6 != nil && r.IPv4.EffectiveMethod() == IPMethodDisabled && r.IPv6.EffectiveMethod() == IPMethodDisabled {
+		err = fmt.Errorf("IPv4 and IPv6 cannot both be disabled")
+	}
+	return
+}
+
+func isIPv6(ip net.IP) bool {
+	return ip.To4() == nil
+}
+
+func inSubnets(subnets []*net.IPNet, ip net.IP) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
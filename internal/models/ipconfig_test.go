@@ -0,0 +1,47 @@
+package models
+
+import "testing"
+
+func TestIPConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     IPConfig
+		ipv6    bool
+		wantErr bool
+	}{
+		{name: "default", cfg: IPConfig{}},
+		{name: "auto with dns", cfg: IPConfig{DNS: []string{"1.1.1.1"}, DNSSearch: []string{"example.com."}}},
+		{name: "manual", cfg: IPConfig{Method: IPMethodManual, Addresses: []string{"192.168.1.20/24"}, Gateway: "192.168.1.1", DNS: []string{"192.168.1.1"}}},
+		{name: "manual without address", cfg: IPConfig{Method: IPMethodManual, Gateway: "192.168.1.1"}, wantErr: true},
+		{name: "address without prefix", cfg: IPConfig{Method: IPMethodManual, Addresses: []string{"192.168.1.20"}}, wantErr: true},
+		{name: "bad prefix", cfg: IPConfig{Method: IPMethodManual, Addresses: []string{"192.168.1.20/33"}}, wantErr: true},
+		{name: "gateway outside the subnet", cfg: IPConfig{Method: IPMethodManual, Addresses: []string{"192.168.1.20/24"}, Gateway: "192.168.2.1"}, wantErr: true},
+		{name: "ipv6 dns on ipv4", cfg: IPConfig{DNS: []string{"2001:4860:4860::8888"}}, wantErr: true},
+		{name: "ipv6 address on ipv4", cfg: IPConfig{Method: IPMethodManual, Addresses: []string{"fd00::20/64"}}, wantErr: true},
+		{name: "address with auto", cfg: IPConfig{Addresses: []string{"192.168.1.20/24"}}, wantErr: true},
+		{name: "dns with disabled", cfg: IPConfig{Method: IPMethodDisabled, DNS: []string{"1.1.1.1"}}, wantErr: true},
+		{name: "unknown method", cfg: IPConfig{Method: "dhcp"}, wantErr: true},
+		{name: "invalid search domain", cfg: IPConfig{DNSSearch: []string{"example..com"}}, wantErr: true},
+		{name: "ipv6 manual with link-local gateway", cfg: IPConfig{Method: IPMethodManual, Addresses: []string{"fd00::20/64"}, Gateway: "fe80::1"}, ipv6: true},
+		{name: "ipv6 bad prefix", cfg: IPConfig{Method: IPMethodManual, Addresses: []string{"fd00::20/129"}}, ipv6: true, wantErr: true},
+		{name: "ipv4 dns on ipv6", cfg: IPConfig{DNS: []string{"1.1.1.1"}}, ipv6: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate(tt.ipv6)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate returned %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateIP(t *testing.T) {
+	disabled := &IPConfig{Method: IPMethodDisabled}
+	if err := (ConnectRequest{IPv4: disabled, IPv6: disabled}).ValidateIP(); err == nil {
+		t.Errorf("both families disabled accepted")
+	}
+	if err := (ConnectRequest{IPv6: disabled}).ValidateIP(); err != nil {
+		t.Errorf("IPv6 disabled refused: %s", err.Error())
+	}
+}
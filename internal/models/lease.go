@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Lease defines an IPv4 address leased to a client of the portal network
+type Lease struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	// Expires is the end of the lease, the client renews it before as long as it stays connected
+	Expires time.Time `json:"expires"`
+}
@@ -1,7 +1,53 @@
 package models
 
+import (
+	"errors"
+	"time"
+)
+
 // AccessPoint defines AccessPoint structure
 type AccessPoint struct {
 	SSID     string `json:"ssid"`
 	Security string `json:"security"`
+	// Strength is the signal quality in percent, SignalDBm its estimate in dBm
+	Strength  uint8 `json:"strength"`
+	SignalDBm int   `json:"signalDbm"`
+	// Frequency (MHz), band and channel of the strongest BSSID
+	Frequency uint32 `json:"frequency"`
+	Band      string `json:"band"`
+	Channel   uint32 `json:"channel"`
+	// Bands lists the bands of every BSSID of the network
+	Bands      []string `json:"bands"`
+	BSSIDCount int      `json:"bssidCount"`
+	// MaxBitrate is the highest bitrate of any BSSID in kbit/s
+	MaxBitrate uint32 `json:"maxBitrate"`
+	// Saved is true when NetworkManager already has a profile for the SSID
+	Saved bool `json:"saved"`
+	// LastSeen is the time of the last scan which reported the network
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// ScanResult defines the result of an on-demand scan
+type ScanResult struct {
+	ScannedAt time.Time     `json:"scannedAt"`
+	Partial   bool          `json:"partial"`
+	Networks  []AccessPoint `json:"networks"`
+}
+
+// ErrSavedNetworkNotFound is returned when no saved network has the requested ID
+var ErrSavedNetworkNotFound = errors.New("saved network not found")
+
+// SavedNetwork defines a wireless connection profile stored by NetworkManager
+type SavedNetwork struct {
+	// ID is the UUID of the profile, Name its NetworkManager connection id
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	SSID     string `json:"ssid"`
+	Security string `json:"security"`
+	Hidden   bool   `json:"hidden"`
+	// Autoconnect profiles are activated by NetworkManager, the highest Priority first
+	Autoconnect bool  `json:"autoconnect"`
+	Priority    int32 `json:"priority"`
+	// LastUsed is the last time the profile was successfully activated, nil when never
+	LastUsed *time.Time `json:"lastUsed"`
 }
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// defaultPortalPrefix is the prefix length of the portal subnet when the gateway is given without one
+const defaultPortalPrefix = 24
+
+// PortalNetworkAuto is the portal gateway selecting a free subnet from the pool whenever the portal is opened
+const PortalNetworkAuto = "auto"
+
+// PortalSubnet is the IPv4 network served to the portal clients
+type PortalSubnet struct {
+	Gateway   net.IP
+	Subnet    *net.IPNet
+	DHCPStart net.IP
+	DHCPEnd   net.IP
+}
+
+// Prefix returns the prefix length of the subnet
+func (s PortalSubnet) Prefix() int {
+	prefix, _ := s.Subnet.Mask.Size()
+	return prefix
+}
+
+// NewPortalSubnet returns the portal layout of a /24 picked automatically, the gateway is the first
+// address and every other host address is handed out by DHCP
+func NewPortalSubnet(subnet *net.IPNet) PortalSubnet {
+	ip := subnet.IP.To4()
+	address := func(last byte) net.IP {
+		return net.IPv4(ip[0], ip[1], ip[2], last).To4()
+	}
+	return PortalSubnet{
+		Gateway:   address(1),
+		Subnet:    &net.IPNet{IP: ip, Mask: net.CIDRMask(24, 32)},
+		DHCPStart: address(2),
+		DHCPEnd:   address(254),
+	}
+}
+
+// IsAutoPortalNetwork tells whether the portal subnet is selected from the pool
+func (c Config) IsAutoPortalNetwork() bool {
+	return c.Gateway == PortalNetworkAuto
+}
+
+// PortalSubnetPool returns the networks the automatic portal subnet is picked from, in order of preference
+func (c Config) PortalSubnetPool() (pool []*net.IPNet, err error) {
+	for _, entry := range strings.Split(c.SubnetPool, ",") {
+		entry = strings.TrimSpace(entry)
+		ip, network, parseErr := net.ParseCIDR(entry)
+		if parseErr != nil || ip.To4() == nil {
+			err = fmt.Errorf("invalid portal subnet pool entry %q, expected an IPv4 network in CIDR notation", entry)
+			return
+		}
+		if prefix, _ := network.Mask.Size(); prefix > 24 {
+			err = fmt.Errorf("portal subnet pool entry %s is smaller than a /24", entry)
+			return
+		}
+		network.IP = network.IP.To4()
+		pool = append(pool, network)
+	}
+	return
+}
+
+// PortalSubnet returns the portal subnet given by the gateway and the DHCP range
+func (c Config) PortalSubnet() (s PortalSubnet, err error) {
+	s.Gateway, s.Subnet, err = c.PortalNetwork()
+	if err != nil {
+		return
+	}
+	s.DHCPStart, s.DHCPEnd, err = c.PortalDHCPRange()
+	if err != nil {
+		return
+	}
+	for _, bound := range []struct {
+		name string
+		ip   net.IP
+	}{{"start", s.DHCPStart}, {"end", s.DHCPEnd}} {
+		if !s.Subnet.Contains(bound.ip) {
+			err = fmt.Errorf("portal DHCP range %s %s is outside of the portal subnet %s", bound.name, bound.ip, s.Subnet)
+			return
+		}
+		if !isHostAddress(s.Subnet, bound.ip) {
+			err = fmt.Errorf("portal DHCP range %s %s is the network or broadcast address of %s", bound.name, bound.ip, s.Subnet)
+			return
+		}
+	}
+	if bytes.Compare(s.DHCPStart, s.Gateway) <= 0 && bytes.Compare(s.Gateway, s.DHCPEnd) <= 0 {
+		err = fmt.Errorf("portal gateway %s lies within the DHCP range %s-%s", s.Gateway, s.DHCPStart, s.DHCPEnd)
+	}
+	return
+}
+
+// PortalNetwork returns the gateway address and the subnet of the portal, the gateway may be given
+// in CIDR notation, a /24 is assumed otherwise
+func (c Config) PortalNetwork() (gateway net.IP, subnet *net.IPNet, err error) {
+	if strings.Contains(c.Gateway, "/") {
+		gateway, subnet, err = net.ParseCIDR(c.Gateway)
+		if err != nil {
+			err = fmt.Errorf("invalid portal gateway %q, expected an IPv4 address or CIDR notation", c.Gateway)
+			return
+		}
+	} else {
+		gateway = net.ParseIP(c.Gateway)
+		if gateway != nil {
+			subnet = &net.IPNet{IP: gateway.Mask(net.CIDRMask(defaultPortalPrefix, 32)), Mask: net.CIDRMask(defaultPortalPrefix, 32)}
+		}
+	}
+	if gateway == nil || gateway.To4() == nil {
+		err = fmt.Errorf("invalid portal gateway %q, expected an IPv4 address or CIDR notation", c.Gateway)
+		return
+	}
+	gateway = gateway.To4()
+	subnet.IP = subnet.IP.To4()
+	if prefix, _ := subnet.Mask.Size(); prefix > 30 {
+		err = fmt.Errorf("portal subnet %s is too small, the prefix has to be /30 or shorter", subnet)
+		return
+	}
+	if !isHostAddress(subnet, gateway) {
+		err = fmt.Errorf("portal gateway %s is the network or broadcast address of %s", gateway, subnet)
+		return
+	}
+	return
+}
+
+// PortalDHCPRange returns the first and last address handed out to the portal clients
+func (c Config) PortalDHCPRange() (start net.IP, end net.IP, err error) {
+	bounds := strings.Split(c.DHCPRange, ",")
+	if len(bounds) != 2 {
+		err = fmt.Errorf("invalid portal DHCP range %q, expected <first address>,<last address>", c.DHCPRange)
+		return
+	}
+	start = net.ParseIP(strings.TrimSpace(bounds[0])).To4()
+	if start == nil {
+		err = fmt.Errorf("invalid portal DHCP range start %q", strings.TrimSpace(bounds[0]))
+		return
+	}
+	end = net.ParseIP(strings.TrimSpace(bounds[1])).To4()
+	if end == nil {
+		err = fmt.Errorf("invalid portal DHCP range end %q", strings.TrimSpace(bounds[1]))
+		return
+	}
+	if bytes.Compare(start, end) > 0 {
+		err = fmt.Errorf("portal DHCP range start %s is after its end %s", start, end)
+	}
+	return
+}
+
+// validatePortalNetwork checks that the gateway and the DHCP range form a usable portal subnet,
+// or that the pool is usable in auto mode
+func (c Config) validatePortalNetwork() (err error) {
+	if c.IsAutoPortalNetwork() {
+		_, err = c.PortalSubnetPool()
+		return
+	}
+	_, err = c.PortalSubnet()
+	return
+}
+
+// isHostAddress tells whether the IPv4 address is neither the network nor the broadcast address of the subnet
+func isHostAddress(subnet *net.IPNet, ip net.IP) bool {
+	ip = ip.To4()
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range broadcast {
+		broadcast[i] = subnet.IP[i] | ^subnet.Mask[i]
+	}
+	return !ip.Equal(subnet.IP) && !ip.Equal(broadcast)
+}
@@ -0,0 +1,58 @@
+package models
+
+import "testing"
+
+func TestValidatePortalNetwork(t *testing.T) {
+	tests := []struct {
+		name       string
+		gateway    string
+		dhcpRange  string
+		subnetPool string
+		wantErr    bool
+	}{
+		{name: "default", gateway: "192.168.42.1", dhcpRange: "192.168.42.2,192.168.42.254"},
+		{name: "cidr gateway", gateway: "10.0.0.1/16", dhcpRange: "10.0.1.1,10.0.255.254"},
+		{name: "invalid gateway", gateway: "192.168.42", dhcpRange: "192.168.42.2,192.168.42.254", wantErr: true},
+		{name: "invalid gateway cidr", gateway: "192.168.42.1/33", dhcpRange: "192.168.42.2,192.168.42.254", wantErr: true},
+		{name: "ipv6 gateway", gateway: "fd42::1", dhcpRange: "192.168.42.2,192.168.42.254", wantErr: true},
+		{name: "subnet too small", gateway: "192.168.42.1/31", dhcpRange: "192.168.42.1,192.168.42.1", wantErr: true},
+		{name: "gateway is network address", gateway: "192.168.42.0", dhcpRange: "192.168.42.2,192.168.42.254", wantErr: true},
+		{name: "range outside subnet", gateway: "192.168.42.1", dhcpRange: "192.168.42.2,192.168.43.254", wantErr: true},
+		{name: "range ends on broadcast", gateway: "192.168.42.1", dhcpRange: "192.168.42.2,192.168.42.255", wantErr: true},
+		{name: "range reversed", gateway: "192.168.42.1", dhcpRange: "192.168.42.254,192.168.42.2", wantErr: true},
+		{name: "gateway within range", gateway: "192.168.42.100", dhcpRange: "192.168.42.2,192.168.42.254", wantErr: true},
+		{name: "range with one bound", gateway: "192.168.42.1", dhcpRange: "192.168.42.2", wantErr: true},
+		{name: "auto", gateway: PortalNetworkAuto, subnetPool: "192.168.42.0/24, 10.42.0.0/16"},
+		{name: "auto with invalid cidr", gateway: PortalNetworkAuto, subnetPool: "192.168.42.0/24,10.42.0.0/40", wantErr: true},
+		{name: "auto with address", gateway: PortalNetworkAuto, subnetPool: "192.168.42.1", wantErr: true},
+		{name: "auto with ipv6 network", gateway: PortalNetworkAuto, subnetPool: "fd42::/48", wantErr: true},
+		{name: "auto with network smaller than a /24", gateway: PortalNetworkAuto, subnetPool: "192.168.42.0/25", wantErr: true},
+		{name: "auto with empty pool", gateway: PortalNetworkAuto, subnetPool: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{Gateway: tt.gateway, DHCPRange: tt.dhcpRange, SubnetPool: tt.subnetPool}
+			err := c.validatePortalNetwork()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validatePortalNetwork returned %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPortalSubnetPool(t *testing.T) {
+	c := Config{SubnetPool: " 192.168.42.7/24,10.42.0.0/16 "}
+	pool, err := c.PortalSubnetPool()
+	if err != nil {
+		t.Fatalf("PortalSubnetPool: %s", err.Error())
+	}
+	want := []string{"192.168.42.0/24", "10.42.0.0/16"}
+	if len(pool) != len(want) {
+		t.Fatalf("pool %v, want %v", pool, want)
+	}
+	for i, network := range pool {
+		if network.String() != want[i] || len(network.IP) != 4 {
+			t.Errorf("pool entry %d is %s, want %s as IPv4", i, network, want[i])
+		}
+	}
+}
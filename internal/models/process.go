@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ProcessState is the supervision state of a helper process
+type ProcessState string
+
+// States of a supervised helper process
+const (
+	// ProcessStarting processes run but did not pass their readiness check yet
+	ProcessStarting ProcessState = "starting"
+	ProcessReady    ProcessState = "ready"
+	// ProcessBackoff processes exited and wait to be restarted
+	ProcessBackoff ProcessState = "backoff"
+	ProcessStopped ProcessState = "stopped"
+)
+
+// ProcessStatus defines the state of a helper process managed by WiFi Connect
+type ProcessStatus struct {
+	Name  string       `json:"name"`
+	State ProcessState `json:"state"`
+	// PID is 0 while the process is not running
+	PID       int        `json:"pid"`
+	Restarts  int        `json:"restarts"`
+	StartedAt *time.Time `json:"startedAt"`
+	// LastError is why the last run ended, empty when it was stopped
+	LastError string `json:"lastError"`
+}
@@ -1,15 +1,37 @@
 package models
 
+import "fmt"
+
 // SECURITY flag for access point security key management
 type SECURITY uint32
 
 // Flags for security
 const (
-	NONE       SECURITY = 0b0000_0000
-	WEP        SECURITY = 0b0000_0001
-	WPA        SECURITY = 0b0000_0010
-	WPA2       SECURITY = 0b0000_0100
-	ENTERPRISE SECURITY = 0b0000_1000
+	NONE          SECURITY = 0b0000_0000
+	WEP           SECURITY = 0b0000_0001
+	WPA           SECURITY = 0b0000_0010
+	WPA2          SECURITY = 0b0000_0100
+	ENTERPRISE    SECURITY = 0b0000_1000
+	SAE           SECURITY = 0b0001_0000
+	OWE           SECURITY = 0b0010_0000
+	ENTERPRISE192 SECURITY = 0b0100_0000
+)
+
+// PMF values of the 802-11-wireless-security.pmf setting
+const (
+	PMFDefault  int32 = 0
+	PMFDisable  int32 = 1
+	PMFOptional int32 = 2
+	PMFRequired int32 = 3
+)
+
+// Security modes of the captive portal network
+const (
+	PortalSecurityOpen       string = "open"
+	PortalSecurityWPA2       string = "wpa2"
+	PortalSecuritySAE        string = "wpa3-sae"
+	PortalSecurityTransition string = "transition"
+	PortalSecurityOWE        string = "owe"
 )
 
 // U32 used to return uint32 flag
@@ -17,18 +39,53 @@ func (d SECURITY) U32() uint32 {
 	return uint32(d)
 }
 
-// String returns string
+// String returns the strongest security mode set in the flags
 func (d SECURITY) String() string {
-	switch d {
-	case WEP:
-		return "web"
-	case WPA:
-		return "wpa"
-	case WPA2:
-		return "wp2"
-	case ENTERPRISE:
+	switch {
+	case (d & ENTERPRISE192) == ENTERPRISE192:
+		return "enterprise-192"
+	case (d & ENTERPRISE) == ENTERPRISE:
 		return "enterprise"
+	case (d&SAE) == SAE && (d&WPA2) == WPA2:
+		return "wpa2-wpa3"
+	case (d & SAE) == SAE:
+		return "wpa3"
+	case (d & OWE) == OWE:
+		return "owe"
+	case (d & WPA2) == WPA2:
+		return "wp2"
+	case (d & WPA) == WPA:
+		return "wpa"
+	case (d & WEP) == WEP:
+		return "web"
 	default:
 		return "none"
 	}
 }
+
+// ParseSecurity returns the security flags of a security mode as reported by String
+func ParseSecurity(s string) (security SECURITY, err error) {
+	switch s {
+	case "enterprise-192":
+		security = ENTERPRISE192
+	case "enterprise":
+		security = ENTERPRISE
+	case "wpa2-wpa3":
+		security = WPA2 | SAE
+	case "wpa3":
+		security = SAE
+	case "owe":
+		security = OWE
+	case "wp2", "wpa2":
+		security = WPA2
+	case "wpa":
+		security = WPA
+	case "web", "wep":
+		security = WEP
+	case "none":
+		security = NONE
+	default:
+		err = fmt.Errorf("unsupported security %q", s)
+	}
+	return
+}
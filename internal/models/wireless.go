@@ -0,0 +1,76 @@
+package models
+
+// Wi-Fi bands reported for access points
+const (
+	Band24GHz string = "2.4GHz"
+	Band5GHz  string = "5GHz"
+	Band6GHz  string = "6GHz"
+)
+
+// Hotspot bands, bg and a are the NetworkManager names of the 2.4 GHz and 5 GHz bands
+const (
+	HotspotBandBG   string = "bg"
+	HotspotBandA    string = "a"
+	HotspotBandAuto string = "auto"
+)
+
+// bandChannels holds the channels an access point can be configured on, per NetworkManager band
+var bandChannels = map[string][]uint32{
+	HotspotBandBG: {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14},
+	HotspotBandA: {36, 40, 44, 48, 52, 56, 60, 64, 100, 104, 108, 112, 116, 120, 124, 128, 132, 136, 140, 144,
+		149, 153, 157, 161, 165},
+}
+
+// BandChannels returns the channels of a NetworkManager band
+func BandChannels(band string) []uint32 {
+	return bandChannels[band]
+}
+
+// ChannelBand returns the NetworkManager band of a channel, empty for an unknown channel
+func ChannelBand(channel uint32) string {
+	for band, channels := range bandChannels {
+		for _, c := range channels {
+			if c == channel {
+				return band
+			}
+		}
+	}
+	return ""
+}
+
+// FrequencyToBand returns the band of a frequency in MHz
+func FrequencyToBand(freq uint32) string {
+	switch {
+	case freq >= 2412 && freq <= 2484:
+		return Band24GHz
+	case freq >= 5150 && freq <= 5895:
+		return Band5GHz
+	case freq >= 5925 && freq <= 7125:
+		return Band6GHz
+	default:
+		return ""
+	}
+}
+
+// FrequencyToChannel returns the channel number of a frequency in MHz
+func FrequencyToChannel(freq uint32) uint32 {
+	switch FrequencyToBand(freq) {
+	case Band24GHz:
+		if freq == 2484 {
+			return 14
+		}
+		return (freq - 2407) / 5
+	case Band5GHz:
+		return (freq - 5000) / 5
+	case Band6GHz:
+		return (freq - 5950) / 5
+	default:
+		return 0
+	}
+}
+
+// StrengthToDBm estimates the signal level in dBm from the strength percentage
+// reported by NetworkManager, which maps -100..-40 dBm linearly onto 0..100
+func StrengthToDBm(strength uint8) int {
+	return -100 + int(strength)*60/100
+}
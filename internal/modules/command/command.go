@@ -1,9 +1,11 @@
 package command
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os/exec"
+	"net"
+	"os"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
@@ -11,92 +13,99 @@ import (
 
 // Command for device network commands.
 type Command struct {
-	Log      *logrus.Logger
-	Commands map[string]*exec.Cmd
-	Cfg      models.ConfigHandler
+	Log       *logrus.Logger
+	Cfg       models.ConfigHandler
+	mu        sync.Mutex
+	processes map[string]*process
 }
 
+// dnsmasqReadinessName is resolved to check dnsmasq answers, any name resolves to the gateway
+const dnsmasqReadinessName = "wifi-connect.portal"
+
 // NewCommand returns access to this module
 func NewCommand(l *logrus.Logger, cfg models.ConfigHandler) *Command {
 	return &Command{
-		Commands: make(map[string]*exec.Cmd),
-		Cfg:      cfg,
-		Log:      l,
+		processes: make(map[string]*process),
+		Cfg:       cfg,
+		Log:       l,
 	}
 }
 
-func (c *Command) processMonitor(id string, cmd *exec.Cmd) {
-	c.Log.Info(fmt.Sprintf("ProcessMonitor got with id - %s", id))
-
-	cmdStdoutReader, err := cmd.StdoutPipe()
-	if err != nil {
-		c.Log.Error(fmt.Sprintf("ProcessMonitor - found error on StdoutPipe %s", err.Error()))
-		panic(err)
-	}
-
-	cmdStderrReader, err := cmd.StderrPipe()
+// StartDnsmasq starts dnsmasq serving the portal subnet on the interface.
+func (c *Command) StartDnsmasq(dInt string, subnet models.PortalSubnet) {
+	c.Log.Info("Start DNS masq")
+	args, err := dnsmasqArgs(c.Cfg.Fetch(), dInt, subnet)
 	if err != nil {
-		c.Log.Error(fmt.Sprintf("ProcessMonitor - found error on StderrPipe %s", err.Error()))
-		panic(err)
+		c.Log.Error(fmt.Sprintf("StartDnsmasq - found error on dnsmasqArgs [%s]", err.Error()))
+		return
 	}
 
-	stdOutScanner := bufio.NewScanner(cmdStdoutReader)
-	go func() {
-		for stdOutScanner.Scan() {
-			c.Log.Info(fmt.Sprintf("ProcessMonitor - Output for [%s|%s] ===> %s", id, cmd.Path, stdOutScanner.Text()))
-		}
-	}()
-
-	stdErrScanner := bufio.NewScanner(cmdStderrReader)
-	go func() {
-		for stdErrScanner.Scan() {
-			c.Log.Error("command", "ProcessMonitor", fmt.Sprintf("ProcessMonitor - Output for [%s|%s] ===> %s", id, cmd.Path, stdErrScanner.Text()))
-		}
-	}()
-
-	err = cmd.Run()
-	if err != nil {
-		c.Log.Error(fmt.Sprintf("ProcessMonitor - found error on cmd.run : %s", err.Error()))
-	}
-	if _, ok := c.Commands[id]; ok {
-		delete(c.Commands, id)
-		c.Log.Info(fmt.Sprintf("ProcessMonitor - [%s] exit.", id))
+	// leases of a previous portal are stale, clients are on other networks by now
+	err = os.Remove(dnsmasqLeaseFile)
+	if err != nil && !os.IsNotExist(err) {
+		c.Log.Warn(fmt.Sprintf("StartDnsmasq - found error on removing %s [%s]", dnsmasqLeaseFile, err.Error()))
 	}
+	c.supervise(processSpec{
+		name:  "dnsmasq",
+		path:  "dnsmasq",
+		args:  args,
+		ready: dnsReady(subnet.Gateway),
+	})
 }
 
-// StartDnsmasq starts dnsmasq.
-func (c *Command) StartDnsmasq(dInt string) {
-	c.Log.Info("Start DNS masq")
-	cfg := c.Cfg.Fetch()
-
+// dnsmasqArgs returns the arguments of dnsmasq serving the portal subnet on the interface
+func dnsmasqArgs(cfg models.Config, dInt string, subnet models.PortalSubnet) (args []string, err error) {
 	// hostapd is enabled, fire up dnsmasq
-	args := []string{
-		fmt.Sprintf("--address=/#/%s", cfg.Gateway), // Don't read the hostnames in /etc/hosts.
-		fmt.Sprintf("--dhcp-range=%s", cfg.DHCPRange),
-		fmt.Sprintf("--dhcp-option=option:router,%s", cfg.Gateway),
+	args = []string{
+		fmt.Sprintf("--address=/#/%s", subnet.Gateway), // Don't read the hostnames in /etc/hosts.
+		fmt.Sprintf("--dhcp-range=%s,%s,%s", subnet.DHCPStart, subnet.DHCPEnd, net.IP(subnet.Subnet.Mask)),
+		fmt.Sprintf("--dhcp-option=option:router,%s", subnet.Gateway),
 		fmt.Sprintf("--interface=%s", dInt),
 		"--keep-in-foreground",
 		"--bind-interfaces",
 		"--except-interface=lo",
 		"--conf-file",
 		"--no-hosts",
+		fmt.Sprintf("--dhcp-leasefile=%s", dnsmasqLeaseFile),
 	}
-
-	cmd := exec.Command("dnsmasq", args...)
-	// add command to the commands map TODO close the readers
-	c.Commands["dnsmasq"] = cmd
-	go c.processMonitor("dnsmasq", cmd)
-}
-
-// KillDNSMasq used to kill dnsmasq
-func (c *Command) KillDNSMasq() {
-	if _, ok := c.Commands["dnsmasq"]; ok {
-		c.Log.Info("Kill DNS masq")
-		cmd := c.Commands["dnsmasq"]
-		err := cmd.Process.Kill()
+	if cfg.IPv6 {
+		var gateway6 net.IP
+		gateway6, _, err = cfg.IPv6Gateway()
 		if err != nil {
-			c.Log.Error(fmt.Sprintf("KillDNSMasq - found error on cmd.Process.Kill : %s", err.Error()))
+			return
 		}
+		args = append(args,
+			fmt.Sprintf("--address=/#/%s", gateway6),
+			"--enable-ra",
+			// stateless autoconfiguration and DHCPv6 leases within the prefix of the interface
+			fmt.Sprintf("--dhcp-range=::1000,::ffff,constructor:%s,slaac,64,1h", dInt),
+		)
 	}
 	return
 }
+
+// KillDNSMasq used to stop dnsmasq, it returns once dnsmasq exited
+func (c *Command) KillDNSMasq() {
+	c.Log.Info("Kill DNS masq")
+	c.stopProcess("dnsmasq")
+}
+
+// GetProcessStatus returns the status of the helper processes
+func (c *Command) GetProcessStatus() (status []models.ProcessStatus) {
+	return c.processStatus()
+}
+
+// dnsReady returns a readiness check passing once the DNS server on the gateway answers
+func dnsReady(gateway net.IP) func(ctx context.Context) error {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", net.JoinHostPort(gateway.String(), "53"))
+		},
+	}
+	return func(ctx context.Context) error {
+		_, err := resolver.LookupHost(ctx, dnsmasqReadinessName)
+		return err
+	}
+}
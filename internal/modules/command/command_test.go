@@ -0,0 +1,61 @@
+package command
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+func TestDnsmasqArgs(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("192.168.42.0/24")
+	portal := models.PortalSubnet{
+		Gateway:   net.IPv4(192, 168, 42, 1).To4(),
+		Subnet:    subnet,
+		DHCPStart: net.IPv4(192, 168, 42, 2).To4(),
+		DHCPEnd:   net.IPv4(192, 168, 42, 254).To4(),
+	}
+	ipv4Args := []string{
+		"--address=/#/192.168.42.1",
+		"--dhcp-range=192.168.42.2,192.168.42.254,255.255.255.0",
+		"--dhcp-option=option:router,192.168.42.1",
+		"--interface=wlan0",
+		"--dhcp-leasefile=" + dnsmasqLeaseFile,
+	}
+	ipv6Args := []string{
+		"--address=/#/fd42:42:42:42::1",
+		"--enable-ra",
+		"--dhcp-range=::1000,::ffff,constructor:wlan0,slaac,64,1h",
+	}
+	tests := []struct {
+		name     string
+		cfg      models.Config
+		want     []string
+		wantNone []string
+		wantErr  bool
+	}{
+		{name: "ipv4", cfg: models.Config{}, want: ipv4Args, wantNone: ipv6Args},
+		{name: "ipv6", cfg: models.Config{IPv6: true, IPv6Prefix: "fd42:42:42:42::/64"}, want: append(ipv4Args, ipv6Args...)},
+		{name: "invalid ipv6 prefix", cfg: models.Config{IPv6: true, IPv6Prefix: "2001:db8::/64"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := dnsmasqArgs(tt.cfg, "wlan0", portal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dnsmasqArgs returned %v, want error %t", err, tt.wantErr)
+			}
+			joined := " " + strings.Join(args, " ") + " "
+			for _, arg := range tt.want {
+				if !strings.Contains(joined, " "+arg+" ") {
+					t.Errorf("argument %s missing from %v", arg, args)
+				}
+			}
+			for _, arg := range tt.wantNone {
+				if strings.Contains(joined, " "+arg+" ") {
+					t.Errorf("argument %s given without IPv6", arg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// dnsmasqLeaseFile keeps the leases of the portal apart from the ones of a system wide dnsmasq
+var dnsmasqLeaseFile = filepath.Join(os.TempDir(), "wifi-connect-dnsmasq.leases")
+
+// GetDnsmasqLeases returns the IPv4 leases handed out by dnsmasq, ordered as in its lease file.
+// Every line holds the expiry time, MAC, IP, hostname and client ID, '*' standing for unknown values.
+func (c *Command) GetDnsmasqLeases() (leases []models.Lease, err error) {
+	leases = []models.Lease{}
+	file, err := os.Open(dnsmasqLeaseFile)
+	if os.IsNotExist(err) {
+		// dnsmasq writes the file with the first lease
+		return leases, nil
+	} else if err != nil {
+		err = fmt.Errorf("found error on opening %s [%s]", dnsmasqLeaseFile, err.Error())
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// DHCPv6 leases follow a duid line and have no MAC
+		if len(fields) < 4 || net.ParseIP(fields[2]).To4() == nil {
+			continue
+		}
+		expires, parseErr := strconv.ParseInt(fields[0], 10, 64)
+		if parseErr != nil {
+			continue
+		}
+		lease := models.Lease{MAC: fields[1], IP: fields[2]}
+		if fields[3] != "*" {
+			lease.Hostname = fields[3]
+		}
+		// 0 stands for an infinite lease
+		if expires != 0 {
+			lease.Expires = time.Unix(expires, 0)
+		}
+		leases = append(leases, lease)
+	}
+	err = scanner.Err()
+	if err != nil {
+		err = fmt.Errorf("found error on reading %s [%s]", dnsmasqLeaseFile, err.Error())
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useLeaseFile points the lease file to the test directory, writing the content unless it is empty
+func useLeaseFile(t *testing.T, content string) {
+	t.Helper()
+	saved := dnsmasqLeaseFile
+	t.Cleanup(func() { dnsmasqLeaseFile = saved })
+	dnsmasqLeaseFile = filepath.Join(t.TempDir(), "dnsmasq.leases")
+	if content == "" {
+		return
+	}
+	err := os.WriteFile(dnsmasqLeaseFile, []byte(content), 0644)
+	if err != nil {
+		t.Fatalf("writing the lease file: %s", err.Error())
+	}
+}
+
+func TestGetDnsmasqLeases(t *testing.T) {
+	useLeaseFile(t, `1760000000 02:00:00:00:00:01 192.168.42.10 laptop 01:02:00:00:00:00:01
+0 02:00:00:00:00:02 192.168.42.11 * *
+duid 00:01:00:01:2c:aa:bb:cc:02:00:00:00:00:03
+1760000000 1234 fd42:42:42:42::1000 phone 00:01:00:01:2c:aa:bb:cc:02:00:00:00:00:03
+garbage
+never 02:00:00:00:00:04 192.168.42.12 tablet *
+1760000100 02:00:00:00:00:05 192.168.42.13
+`)
+	leases, err := newTestCommand().GetDnsmasqLeases()
+	if err != nil {
+		t.Fatalf("GetDnsmasqLeases: %s", err.Error())
+	}
+	if len(leases) != 2 {
+		t.Fatalf("leases %+v, want the 2 IPv4 leases", leases)
+	}
+	if l := leases[0]; l.MAC != "02:00:00:00:00:01" || l.IP != "192.168.42.10" || l.Hostname != "laptop" || !l.Expires.Equal(time.Unix(1760000000, 0)) {
+		t.Errorf("lease %+v", l)
+	}
+	// an unknown hostname is left empty, expiry 0 is an infinite lease
+	if l := leases[1]; l.MAC != "02:00:00:00:00:02" || l.IP != "192.168.42.11" || l.Hostname != "" || !l.Expires.IsZero() {
+		t.Errorf("lease %+v, want no hostname and no expiry", l)
+	}
+}
+
+func TestGetDnsmasqLeasesWithoutFile(t *testing.T) {
+	useLeaseFile(t, "")
+	leases, err := newTestCommand().GetDnsmasqLeases()
+	if err != nil || leases == nil || len(leases) != 0 {
+		t.Errorf("leases %v and error %v before the first lease, want an empty list", leases, err)
+	}
+}
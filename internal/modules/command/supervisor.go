@@ -0,0 +1,256 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+var (
+	// initialBackoff is the delay before the first restart, doubled on every failed run up to maxBackoff
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+	// stableRunTime resets the backoff once a run lasted that long
+	stableRunTime = 30 * time.Second
+	// terminateGracePeriod is the time given to exit after SIGTERM before SIGKILL
+	terminateGracePeriod = 5 * time.Second
+	readinessTimeout     = 10 * time.Second
+	readinessInterval    = 200 * time.Millisecond
+)
+
+var errStopped = errors.New("stopped")
+
+// processSpec describes a helper process to supervise
+type processSpec struct {
+	name string
+	path string
+	args []string
+	// ready returns nil once the process serves, it is restarted when not ready within readinessTimeout
+	ready func(ctx context.Context) error
+}
+
+// process is a supervised helper process, its status is guarded by the mutex of the Command
+type process struct {
+	spec     processSpec
+	status   models.ProcessStatus
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// supervise starts the process and restarts it whenever it exits, until stopped.
+// A process of the same name still running is stopped first.
+func (c *Command) supervise(spec processSpec) {
+	c.stopProcess(spec.name)
+	p := &process{
+		spec:   spec,
+		status: models.ProcessStatus{Name: spec.name, State: models.ProcessStarting},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	c.mu.Lock()
+	c.processes[spec.name] = p
+	c.mu.Unlock()
+	go c.run(p)
+}
+
+// stopProcess stops the process, waits for it to exit and forgets it
+func (c *Command) stopProcess(name string) {
+	c.mu.Lock()
+	p, ok := c.processes[name]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.done
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// the process may have been replaced while stopping
+	if c.processes[name] == p {
+		delete(c.processes, name)
+	}
+}
+
+// processStatus returns the status of every supervised process, ordered by name
+func (c *Command) processStatus() (status []models.ProcessStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status = make([]models.ProcessStatus, 0, len(c.processes))
+	for _, p := range c.processes {
+		status = append(status, p.status)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return
+}
+
+func (c *Command) updateStatus(p *process, update func(status *models.ProcessStatus)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&p.status)
+}
+
+// run is the supervision loop of the process
+func (c *Command) run(p *process) {
+	defer close(p.done)
+	backoff := initialBackoff
+	for {
+		startedAt := time.Now()
+		err := c.runOnce(p)
+		if err == errStopped {
+			c.Log.Info(fmt.Sprintf("supervisor - [%s] stopped", p.spec.name))
+			c.updateStatus(p, func(status *models.ProcessStatus) {
+				status.State, status.PID, status.LastError = models.ProcessStopped, 0, ""
+			})
+			return
+		}
+		if time.Since(startedAt) >= stableRunTime {
+			backoff = initialBackoff
+		}
+		c.Log.Error(fmt.Sprintf("supervisor - [%s] found error on run [%s], restarting in %s", p.spec.name, err.Error(), backoff))
+		c.updateStatus(p, func(status *models.ProcessStatus) {
+			status.State, status.PID, status.LastError = models.ProcessBackoff, 0, err.Error()
+		})
+		select {
+		case <-p.stop:
+			c.updateStatus(p, func(status *models.ProcessStatus) {
+				status.State = models.ProcessStopped
+			})
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		c.updateStatus(p, func(status *models.ProcessStatus) {
+			status.Restarts++
+		})
+	}
+}
+
+// runOnce runs the process until it exits, fails its readiness check or is stopped
+func (c *Command) runOnce(p *process) (err error) {
+	cmd := exec.Command(p.spec.path, p.spec.args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("found error on StdoutPipe [%s]", err.Error())
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("found error on StderrPipe [%s]", err.Error())
+	}
+	err = cmd.Start()
+	if err != nil {
+		return fmt.Errorf("found error on Start [%s]", err.Error())
+	}
+	startedAt := time.Now()
+	c.Log.Info(fmt.Sprintf("supervisor - [%s] started with pid %d", p.spec.name, cmd.Process.Pid))
+	c.updateStatus(p, func(status *models.ProcessStatus) {
+		status.State, status.PID, status.StartedAt = models.ProcessStarting, cmd.Process.Pid, &startedAt
+	})
+
+	// Wait closes the pipes, it is only called once the output is read
+	var output sync.WaitGroup
+	output.Add(2)
+	go c.logOutput(&output, p.spec.name, stdout, false)
+	go c.logOutput(&output, p.spec.name, stderr, true)
+	exited := make(chan error, 1)
+	go func() {
+		output.Wait()
+		exited <- cmd.Wait()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ready := make(chan error, 1)
+	go func() {
+		ready <- c.waitReady(ctx, p.spec)
+	}()
+
+	for {
+		select {
+		case err = <-exited:
+			if err == nil {
+				err = errors.New("exit status 0")
+			}
+			return
+		case err = <-ready:
+			if err == nil {
+				c.Log.Info(fmt.Sprintf("supervisor - [%s] ready", p.spec.name))
+				c.updateStatus(p, func(status *models.ProcessStatus) {
+					status.State = models.ProcessReady
+				})
+				continue
+			}
+			c.terminate(p.spec.name, cmd, exited)
+			return fmt.Errorf("not ready after %s [%s]", readinessTimeout, err.Error())
+		case <-p.stop:
+			c.terminate(p.spec.name, cmd, exited)
+			return errStopped
+		}
+	}
+}
+
+// waitReady polls the readiness check of the process until it passes or readinessTimeout elapses
+func (c *Command) waitReady(ctx context.Context, spec processSpec) (err error) {
+	if spec.ready == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	ticker := time.NewTicker(readinessInterval)
+	defer ticker.Stop()
+	for {
+		err = spec.ready(ctx)
+		if err == nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// terminate sends SIGTERM to the process and SIGKILL when it did not exit after terminateGracePeriod
+func (c *Command) terminate(name string, cmd *exec.Cmd, exited <-chan error) {
+	err := cmd.Process.Signal(syscall.SIGTERM)
+	if err != nil {
+		c.Log.Warn(fmt.Sprintf("supervisor - [%s] found error on SIGTERM [%s]", name, err.Error()))
+	}
+	select {
+	case <-exited:
+		return
+	case <-time.After(terminateGracePeriod):
+	}
+	c.Log.Warn(fmt.Sprintf("supervisor - [%s] still running %s after SIGTERM, sending SIGKILL", name, terminateGracePeriod))
+	err = cmd.Process.Kill()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("supervisor - [%s] found error on SIGKILL [%s]", name, err.Error()))
+	}
+	<-exited
+}
+
+// logOutput logs every line written by the process, its standard error as errors
+func (c *Command) logOutput(wg *sync.WaitGroup, name string, r io.Reader, isStderr bool) {
+	defer wg.Done()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if isStderr {
+			c.Log.Error(fmt.Sprintf("supervisor - [%s] ===> %s", name, scanner.Text()))
+		} else {
+			c.Log.Info(fmt.Sprintf("supervisor - [%s] ===> %s", name, scanner.Text()))
+		}
+	}
+}
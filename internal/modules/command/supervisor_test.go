@@ -0,0 +1,264 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// shortTimings shortens the supervision delays for the test
+func shortTimings(t *testing.T) {
+	saved := []*time.Duration{&initialBackoff, &maxBackoff, &stableRunTime, &terminateGracePeriod, &readinessTimeout, &readinessInterval}
+	values := make([]time.Duration, len(saved))
+	for i, v := range saved {
+		values[i] = *v
+	}
+	t.Cleanup(func() {
+		for i, v := range saved {
+			*v = values[i]
+		}
+	})
+	initialBackoff = 50 * time.Millisecond
+	maxBackoff = 200 * time.Millisecond
+	stableRunTime = time.Minute
+	terminateGracePeriod = 300 * time.Millisecond
+	readinessTimeout = 300 * time.Millisecond
+	readinessInterval = 10 * time.Millisecond
+}
+
+func newTestCommand() *Command {
+	l := logrus.New()
+	l.Out = io.Discard
+	return NewCommand(l, &models.Config{})
+}
+
+// stubBinary writes an executable shell script to the test directory
+func stubBinary(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stub")
+	err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	if err != nil {
+		t.Fatalf("writing the stub binary: %s", err.Error())
+	}
+	return path
+}
+
+// waitStatus polls the status of the process until the condition holds
+func waitStatus(t *testing.T, c *Command, name string, timeout time.Duration, condition func(status models.ProcessStatus) bool) models.ProcessStatus {
+	t.Helper()
+	var last models.ProcessStatus
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		for _, status := range c.GetProcessStatus() {
+			if status.Name == name {
+				last = status
+				if condition(status) {
+					return status
+				}
+			}
+		}
+	}
+	t.Fatalf("status of %s is %+v after %s", name, last, timeout)
+	return last
+}
+
+// assertForgotten checks the process is stopped, reaped and no longer listed
+func assertForgotten(t *testing.T, c *Command, pid int) {
+	t.Helper()
+	if status := c.GetProcessStatus(); len(status) != 0 {
+		t.Errorf("status %+v after stop, want none", status)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.processes) != 0 {
+		t.Errorf("%d processes kept after stop", len(c.processes))
+	}
+	if pid != 0 {
+		if err := syscall.Kill(pid, 0); err != syscall.ESRCH {
+			t.Errorf("pid %d still exists after stop, signal 0 returned %v", pid, err)
+		}
+	}
+}
+
+// startTimes returns the times written by the stub script at every start
+func startTimes(t *testing.T, path string) (times []time.Time) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the start times: %s", err.Error())
+	}
+	for _, line := range strings.Fields(string(data)) {
+		ns, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			t.Fatalf("start time %q: %s", line, err.Error())
+		}
+		times = append(times, time.Unix(0, ns))
+	}
+	return
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	shortTimings(t)
+	starts := filepath.Join(t.TempDir(), "starts")
+	c := newTestCommand()
+	c.supervise(processSpec{
+		name: "stub",
+		path: stubBinary(t, "date +%s%N >> "+starts+"\necho failing >&2\nexit 3"),
+	})
+	// Restarts is counted before the next run, the fifth restart follows the fifth start
+	status := waitStatus(t, c, "stub", 5*time.Second, func(status models.ProcessStatus) bool { return status.Restarts >= 5 })
+	c.stopProcess("stub")
+	if status.LastError != "exit status 3" {
+		t.Errorf("last error %q, want exit status 3", status.LastError)
+	}
+	assertForgotten(t, c, 0)
+
+	times := startTimes(t, starts)
+	if len(times) < 5 {
+		t.Fatalf("%d starts, want at least 5", len(times))
+	}
+	// the delay doubles from initialBackoff and stays at maxBackoff
+	wantDelays := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond, 200 * time.Millisecond}
+	for i, want := range wantDelays {
+		if delay := times[i+1].Sub(times[i]); delay < want || delay > want+time.Second {
+			t.Errorf("restart %d after %s, want %s", i+1, delay, want)
+		}
+	}
+}
+
+func TestSupervisorBackoffReset(t *testing.T) {
+	shortTimings(t)
+	initialBackoff = 150 * time.Millisecond
+	maxBackoff = 2 * time.Second
+	stableRunTime = 100 * time.Millisecond
+	starts := filepath.Join(t.TempDir(), "starts")
+	c := newTestCommand()
+	c.supervise(processSpec{name: "stub", path: stubBinary(t, "date +%s%N >> "+starts+"\nsleep 0.2\nexit 1")})
+	waitStatus(t, c, "stub", 5*time.Second, func(status models.ProcessStatus) bool { return status.Restarts >= 4 })
+	c.stopProcess("stub")
+	assertForgotten(t, c, 0)
+
+	times := startTimes(t, starts)
+	if len(times) < 4 {
+		t.Fatalf("%d starts, want at least 4", len(times))
+	}
+	// every run lasts longer than stableRunTime, so the delay stays at initialBackoff instead of doubling
+	for i := 1; i < 4; i++ {
+		if delay := times[i].Sub(times[i-1]); delay > 200*time.Millisecond+2*initialBackoff {
+			t.Errorf("restart %d after %s, want a run of 200ms and %s", i, delay, initialBackoff)
+		}
+	}
+}
+
+func TestSupervisorStop(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		// wantKill tells whether the process outlives SIGTERM and gets SIGKILL
+		wantKill bool
+	}{
+		{name: "exits on SIGTERM", script: "exec sleep 60"},
+		// ignored signals stay ignored across exec
+		{name: "ignores SIGTERM", script: "trap '' TERM\nexec sleep 60", wantKill: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shortTimings(t)
+			c := newTestCommand()
+			c.supervise(processSpec{name: "stub", path: stubBinary(t, tt.script)})
+			status := waitStatus(t, c, "stub", 5*time.Second, func(status models.ProcessStatus) bool { return status.PID != 0 })
+			// let the shell set up its trap
+			time.Sleep(50 * time.Millisecond)
+
+			startedAt := time.Now()
+			c.stopProcess("stub")
+			elapsed := time.Since(startedAt)
+			if killed := elapsed >= terminateGracePeriod; killed != tt.wantKill {
+				t.Errorf("stopped in %s, want SIGKILL after %s %t", elapsed, terminateGracePeriod, tt.wantKill)
+			}
+			if elapsed > terminateGracePeriod+2*time.Second {
+				t.Errorf("stopped in %s", elapsed)
+			}
+			assertForgotten(t, c, status.PID)
+		})
+	}
+}
+
+func TestSupervisorReadiness(t *testing.T) {
+	shortTimings(t)
+	var checks int32
+	c := newTestCommand()
+	c.supervise(processSpec{
+		name: "stub",
+		path: stubBinary(t, "exec sleep 60"),
+		ready: func(ctx context.Context) error {
+			if atomic.AddInt32(&checks, 1) < 3 {
+				return errors.New("not answering")
+			}
+			return nil
+		},
+	})
+	status := waitStatus(t, c, "stub", 5*time.Second, func(status models.ProcessStatus) bool { return status.State == models.ProcessReady })
+	// a ready process keeps running
+	time.Sleep(readinessTimeout + 100*time.Millisecond)
+	ready := waitStatus(t, c, "stub", time.Second, func(models.ProcessStatus) bool { return true })
+	if ready.State != models.ProcessReady || ready.PID != status.PID || ready.Restarts != 0 {
+		t.Errorf("status %+v, want ready pid %d without restart", ready, status.PID)
+	}
+	c.stopProcess("stub")
+	assertForgotten(t, c, status.PID)
+}
+
+func TestSupervisorNeverReady(t *testing.T) {
+	shortTimings(t)
+	c := newTestCommand()
+	c.supervise(processSpec{
+		name:  "stub",
+		path:  stubBinary(t, "exec sleep 60"),
+		ready: func(ctx context.Context) error { return errors.New("not answering") },
+	})
+	first := waitStatus(t, c, "stub", 5*time.Second, func(status models.ProcessStatus) bool { return status.PID != 0 })
+	status := waitStatus(t, c, "stub", 5*time.Second, func(status models.ProcessStatus) bool { return status.Restarts >= 1 && status.PID != 0 })
+	if status.PID == first.PID || status.State != models.ProcessStarting || !strings.HasPrefix(status.LastError, "not ready after") {
+		t.Errorf("status %+v after the readiness timeout, want a new starting process", status)
+	}
+	if err := syscall.Kill(first.PID, 0); err != syscall.ESRCH {
+		t.Errorf("unready pid %d still exists, signal 0 returned %v", first.PID, err)
+	}
+	c.stopProcess("stub")
+	assertForgotten(t, c, status.PID)
+}
+
+func TestSupervisorReplace(t *testing.T) {
+	shortTimings(t)
+	c := newTestCommand()
+	c.supervise(processSpec{name: "stub", path: stubBinary(t, "exec sleep 60")})
+	first := waitStatus(t, c, "stub", 5*time.Second, func(status models.ProcessStatus) bool { return status.PID != 0 })
+	c.supervise(processSpec{name: "stub", path: stubBinary(t, "exec sleep 60")})
+	second := waitStatus(t, c, "stub", 5*time.Second, func(status models.ProcessStatus) bool { return status.PID != 0 })
+	if second.PID == first.PID {
+		t.Fatalf("pid %d kept when supervising again", first.PID)
+	}
+	if err := syscall.Kill(first.PID, 0); err != syscall.ESRCH {
+		t.Errorf("replaced pid %d still exists, signal 0 returned %v", first.PID, err)
+	}
+	if status := c.GetProcessStatus(); len(status) != 1 {
+		t.Errorf("status %+v, want the new process only", status)
+	}
+	c.KillDNSMasq()
+	if status := c.GetProcessStatus(); len(status) != 1 {
+		t.Errorf("status %+v, killing dnsmasq stopped another process", status)
+	}
+	c.stopProcess("stub")
+	assertForgotten(t, c, second.PID)
+}
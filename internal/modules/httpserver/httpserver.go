@@ -1,7 +1,9 @@
 package httpserver
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -13,6 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
 	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/qrcode"
 )
 
 // HTTPServer represents a module that provides an https protocol
@@ -34,13 +37,6 @@ type spaHandler struct {
 	indexPath  string
 }
 
-// ConnectRequest request object for connect
-type ConnectRequest struct {
-	Identity   string `json:"identity"`
-	Passphrase string `json:"passphrase"`
-	SSID       string `json:"ssid"`
-}
-
 // NewHTTPServer creates an HTTP health checker
 func NewHTTPServer(l *logrus.Logger, nw interfaces.Network, cfg models.ConfigHandler) *HTTPServer {
 	return &HTTPServer{
@@ -75,14 +71,21 @@ func (h *HTTPServer) Handler() http.Handler {
 	router := mux.NewRouter()
 	cfg := h.Cfg.Fetch()
 	router.HandleFunc("/networks", h.GetNetworks).Methods("GET")
+	router.HandleFunc("/networks/scan", h.ScanNetworks).Methods("POST")
 	router.HandleFunc("/connect", h.Connect).Methods("POST")
+	router.HandleFunc("/saved-networks", h.GetSavedNetworks).Methods("GET")
+	router.HandleFunc("/saved-networks/{id}", h.ForgetSavedNetwork).Methods("DELETE")
+	router.HandleFunc("/saved-networks/{id}/priority", h.SetSavedNetworkPriority).Methods("PUT")
+	router.HandleFunc("/portal/qrcode.{format:png|svg}", h.GetQRCode).Methods("GET")
+	router.HandleFunc("/portal/processes", h.GetProcesses).Methods("GET")
+	router.HandleFunc("/portal/clients", h.GetPortalClients).Methods("GET")
 
 	spa := spaHandler{staticPath: cfg.UIDirectory, indexPath: "index.html"}
 	router.PathPrefix("/").Handler(spa)
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedHeaders: []string{"Content-Type", "Authorization", "Content-Length", "X-Requested-With", "Accept", "Origin"},
-		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 	})
 	handler := c.Handler(router)
 	return handler
@@ -91,11 +94,13 @@ func (h *HTTPServer) Handler() http.Handler {
 //StartHTTPServer retuns http.Server
 func (h *HTTPServer) StartHTTPServer() {
 	h.Log.Info("Start HTTP Server")
+	// the wildcard address accepts both IPv4 and IPv6 clients, the portal IPv6 address included
 	s := &http.Server{
 		Addr:         fmt.Sprintf(":%s", h.Cfg.Fetch().Port),
 		Handler:      h.Handler(),
 		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+		// scans may take a while, especially when the hotspot is restarted for them
+		WriteTimeout: 60 * time.Second,
 		IdleTimeout:  5 * time.Second,
 	}
 
@@ -132,26 +137,190 @@ func (h *HTTPServer) GetNetworks(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, ap)
 }
 
+// ScanNetworks method used to rescan networks and return the refreshed list
+func (h *HTTPServer) ScanNetworks(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("'ScanNetworks' called via http request")
+	result, err := h.NetworkManager.Scan(r.Context())
+	if err != nil {
+		h.Log.Error(fmt.Sprintf("ScanNetworks - found error on Scan: %s", err.Error()))
+		respondWithError(w, 500, "Internal Error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
 // Connect method used to connect
 func (h *HTTPServer) Connect(w http.ResponseWriter, r *http.Request) {
 	h.Log.Info("'Connect' called via http request")
 	decoder := json.NewDecoder(r.Body)
-	var req ConnectRequest
+	var req models.ConnectRequest
 	err := decoder.Decode(&req)
 	if err != nil {
 		h.Log.Error(fmt.Sprintf("Connect - found error on extract request body: %s", err.Error()))
-		respondWithError(w, 400, "Bad Request")
+		respondWithConnectError(w, models.NewConnectError(models.ConnectErrorInvalidRequest, "%s", err.Error()))
 		return
 	}
-	ok := h.NetworkManager.Connect(req.SSID, req.Passphrase, req.Identity)
-	if !ok {
-		respondWithJSON(w, 500, "internal error")
+	err = h.NetworkManager.Connect(req)
+	if err != nil {
+		h.Log.Error(fmt.Sprintf("Connect - found error on Connect: %s", err.Error()))
+		respondWithConnectError(w, err)
 		return
 	}
 	respondWithJSON(w, 200, nil)
+	go h.exitAfterResponse()
+}
+
+// exitAfterResponse waits for the pending responses to be sent, closes the portal and exits.
+// In dual-radio mode the portal is still up at this point, the client learns the result through it.
+func (h *HTTPServer) exitAfterResponse() {
+	if h.Server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := h.Server.Shutdown(ctx)
+		if err != nil {
+			h.Log.Warn(fmt.Sprintf("exitAfterResponse - found error on Shutdown: %s", err.Error()))
+		}
+	}
+	h.NetworkManager.CloseHotSpot()
 	os.Exit(0)
 }
 
+// priorityRequest is the body of a saved network priority change
+type priorityRequest struct {
+	Priority *int32 `json:"priority"`
+}
+
+// GetSavedNetworks method used to list the network profiles saved by NetworkManager
+func (h *HTTPServer) GetSavedNetworks(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("'GetSavedNetworks' called via http request")
+	networks, err := h.NetworkManager.GetSavedNetworks()
+	if err != nil {
+		respondWithError(w, 500, "Internal Error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, networks)
+}
+
+// ForgetSavedNetwork method used to delete a saved network profile
+func (h *HTTPServer) ForgetSavedNetwork(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("'ForgetSavedNetwork' called via http request")
+	err := h.NetworkManager.ForgetSavedNetwork(mux.Vars(r)["id"])
+	if err == models.ErrSavedNetworkNotFound {
+		respondWithError(w, 404, err.Error())
+		return
+	} else if err != nil {
+		respondWithError(w, 500, "Internal Error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, nil)
+}
+
+// SetSavedNetworkPriority method used to change the autoconnect priority of a saved network profile
+func (h *HTTPServer) SetSavedNetworkPriority(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("'SetSavedNetworkPriority' called via http request")
+	var req priorityRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.Priority == nil {
+		respondWithError(w, 400, "Bad Request")
+		return
+	}
+	err = h.NetworkManager.SetSavedNetworkPriority(mux.Vars(r)["id"], *req.Priority)
+	if err == models.ErrSavedNetworkNotFound {
+		respondWithError(w, 404, err.Error())
+		return
+	} else if err != nil {
+		respondWithError(w, 500, "Internal Error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, nil)
+}
+
+// qrCodeScale is the width of a module of the served QR codes, in pixels for PNG
+const qrCodeScale = 8
+
+// GetQRCode method used to retrieve the QR code for joining the portal network, as PNG or SVG
+func (h *HTTPServer) GetQRCode(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("'GetQRCode' called via http request")
+	hotspot, err := h.NetworkManager.GetHotSpot()
+	if err == models.ErrHotSpotNotActive {
+		respondWithError(w, 503, err.Error())
+		return
+	} else if err != nil {
+		respondWithError(w, 500, "Internal Error")
+		return
+	}
+	code, err := qrcode.Encode([]byte(hotspot.WifiQRText()))
+	if err != nil {
+		h.Log.Error(fmt.Sprintf("GetQRCode - found error on Encode: %s", err.Error()))
+		respondWithError(w, 500, "Internal Error")
+		return
+	}
+	var image []byte
+	contentType := "image/svg+xml"
+	if mux.Vars(r)["format"] == "png" {
+		contentType = "image/png"
+		image, err = code.PNG(qrCodeScale)
+		if err != nil {
+			h.Log.Error(fmt.Sprintf("GetQRCode - found error on PNG: %s", err.Error()))
+			respondWithError(w, 500, "Internal Error")
+			return
+		}
+	} else {
+		image = code.SVG(qrCodeScale)
+	}
+	w.Header().Set("Content-Type", contentType)
+	// the code holds the passphrase, which may be rotated
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+// GetProcesses method used to retrieve the status of the helper processes of the portal
+func (h *HTTPServer) GetProcesses(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("'GetProcesses' called via http request")
+	respondWithJSON(w, http.StatusOK, h.NetworkManager.GetProcessStatus())
+}
+
+// GetPortalClients method used to list the clients holding a lease on the portal network
+func (h *HTTPServer) GetPortalClients(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("'GetPortalClients' called via http request")
+	clients, err := h.NetworkManager.GetPortalClients()
+	if err == models.ErrHotSpotNotActive {
+		respondWithError(w, 503, err.Error())
+		return
+	} else if err != nil {
+		h.Log.Error(fmt.Sprintf("GetPortalClients - found error on GetPortalClients: %s", err.Error()))
+		respondWithError(w, 500, "Internal Error")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, clients)
+}
+
+// connectErrorStatus holds the HTTP status answered for every connect error code
+var connectErrorStatus = map[models.ConnectErrorCode]int{
+	models.ConnectErrorInvalidRequest:   http.StatusBadRequest,
+	models.ConnectErrorAuthFailed:       http.StatusUnauthorized,
+	models.ConnectErrorAPNotFound:       http.StatusNotFound,
+	models.ConnectErrorDHCPFailed:       http.StatusBadGateway,
+	models.ConnectErrorActivationFailed: http.StatusBadGateway,
+	models.ConnectErrorNoConnectivity:   http.StatusBadGateway,
+	models.ConnectErrorTimeout:          http.StatusGatewayTimeout,
+}
+
+// respondWithConnectError answers with the code of the connect error and its user facing message
+func respondWithConnectError(w http.ResponseWriter, err error) {
+	code := models.ConnectErrorInternal
+	var connectErr *models.ConnectError
+	if errors.As(err, &connectErr) {
+		code = connectErr.Code
+	}
+	status, ok := connectErrorStatus[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	respondWithJSON(w, status, map[string]string{"error": code.Message(), "code": string(code)})
+}
+
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
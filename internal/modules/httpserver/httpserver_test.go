@@ -0,0 +1,95 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// testNetwork answers Connect with a fixed error, the other methods are not used by the tests
+type testNetwork struct {
+	interfaces.Network
+	connectErr error
+	connects   int
+}
+
+func (t *testNetwork) Connect(req models.ConnectRequest) error {
+	t.connects++
+	return t.connectErr
+}
+
+func TestConnectErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		connectErr error
+		wantStatus int
+		wantCode   models.ConnectErrorCode
+		// wantConnect tells whether the request reaches Connect
+		wantConnect bool
+	}{
+		{
+			name:       "malformed body",
+			body:       `{"ssid": "home", "passphrase": `,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   models.ConnectErrorInvalidRequest,
+		},
+		{
+			name:        "invalid request",
+			body:        `{"ssid": "attic", "hidden": true}`,
+			connectErr:  models.NewConnectError(models.ConnectErrorInvalidRequest, "security is required for hidden networks"),
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    models.ConnectErrorInvalidRequest,
+			wantConnect: true,
+		},
+		{
+			name:        "authentication failed",
+			body:        `{"ssid": "home", "passphrase": "wrong-secret"}`,
+			connectErr:  models.NewConnectError(models.ConnectErrorAuthFailed, ""),
+			wantStatus:  http.StatusUnauthorized,
+			wantCode:    models.ConnectErrorAuthFailed,
+			wantConnect: true,
+		},
+		{
+			name:        "uncoded error",
+			body:        `{"ssid": "home", "passphrase": "home-secret"}`,
+			connectErr:  errors.New("dbus connection lost"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    models.ConnectErrorInternal,
+			wantConnect: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := logrus.New()
+			l.Out = io.Discard
+			nw := &testNetwork{connectErr: tt.connectErr}
+			h := NewHTTPServer(l, nw, nil)
+
+			w := httptest.NewRecorder()
+			h.Connect(w, httptest.NewRequest("POST", "/connect", strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			var body map[string]string
+			err := json.Unmarshal(w.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("response %q: %s", w.Body.String(), err.Error())
+			}
+			if body["code"] != string(tt.wantCode) || body["error"] != tt.wantCode.Message() {
+				t.Errorf("response %v, want code %s", body, tt.wantCode)
+			}
+			if connected := nw.connects > 0; connected != tt.wantConnect {
+				t.Errorf("Connect called %t, want %t", connected, tt.wantConnect)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// autoChannels are the channels considered by the auto channel policy: the non-overlapping 2.4 GHz channels
+// and the 5 GHz channels usable in every regulatory domain without radar detection
+var autoChannels = map[string][]uint32{
+	models.HotspotBandBG: {1, 6, 11},
+	models.HotspotBandA:  {36, 40, 44, 48},
+}
+
+// hotSpotBandFrequencies maps the NetworkManager bands onto the bands of the scanned frequencies
+var hotSpotBandFrequencies = map[string]string{
+	models.HotspotBandBG: models.Band24GHz,
+	models.HotspotBandA:  models.Band5GHz,
+}
+
+// bssRadio is the band, channel and signal of one BSSID of the scan
+type bssRadio struct {
+	band     string
+	channel  uint32
+	strength uint8
+}
+
+// newBSSRadio returns the radio of a BSSID seen on the frequency in MHz
+func newBSSRadio(frequency uint32, strength uint8) bssRadio {
+	return bssRadio{band: models.FrequencyToBand(frequency), channel: models.FrequencyToChannel(frequency), strength: strength}
+}
+
+// channelScore is the congestion of a channel, every overlapping BSSID counts for 1 to 2 depending on its signal
+type channelScore struct {
+	channel uint32
+	bss     int
+	score   float64
+}
+
+func (s channelScore) String() string {
+	return fmt.Sprintf("%d: %d BSSIDs, score %.2f", s.channel, s.bss, s.score)
+}
+
+// leastCongestedChannel picks the channel of the band least used by the access points of the last scan.
+// The BSSIDs of hidden networks are not part of the scan list and are left out.
+func (c *Config) leastCongestedChannel(band string) uint32 {
+	var radios []bssRadio
+	c.apMu.Lock()
+	for _, ap := range c.AccessPoints {
+		for _, radio := range ap.radios {
+			if radio.band == hotSpotBandFrequencies[band] {
+				radios = append(radios, radio)
+			}
+		}
+	}
+	c.apMu.Unlock()
+	scores := scoreChannels(band, autoChannels[band], radios)
+	best := scores[0]
+	descriptions := make([]string, len(scores))
+	for i, score := range scores {
+		descriptions[i] = score.String()
+		if score.score < best.score {
+			best = score
+		}
+	}
+	c.Log.Info(fmt.Sprintf("leastCongestedChannel - picked channel %d out of %d BSSIDs in band %s [%s]", best.channel, len(radios), band, strings.Join(descriptions, "; ")))
+	return best.channel
+}
+
+// scoreChannels returns the congestion of every candidate channel
+func scoreChannels(band string, candidates []uint32, radios []bssRadio) (scores []channelScore) {
+	for _, channel := range candidates {
+		score := channelScore{channel: channel}
+		for _, radio := range radios {
+			overlap := channelOverlap(band, channel, radio.channel)
+			if overlap == 0 {
+				continue
+			}
+			score.bss++
+			score.score += overlap * (1 + float64(radio.strength)/100)
+		}
+		scores = append(scores, score)
+	}
+	return
+}
+
+// channelOverlap returns how much two channels of the band overlap, from 0 to 1.
+// 2.4 GHz channels are 5 MHz apart and 20 MHz wide, 5 GHz channels do not overlap.
+func channelOverlap(band string, a uint32, b uint32) float64 {
+	distance := int(a) - int(b)
+	if distance < 0 {
+		distance = -distance
+	}
+	if band == models.HotspotBandBG {
+		if distance >= 5 {
+			return 0
+		}
+		return float64(5-distance) / 5
+	}
+	if distance == 0 {
+		return 1
+	}
+	return 0
+}
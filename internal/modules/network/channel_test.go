@@ -0,0 +1,173 @@
+package network
+
+import (
+	"math"
+	"testing"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+func TestScoreChannels(t *testing.T) {
+	tests := []struct {
+		name   string
+		band   string
+		radios []bssRadio
+		// want holds the BSSIDs counted and the score of every auto channel of the band
+		want []channelScore
+	}{
+		{
+			name: "empty 2.4 GHz band",
+			band: models.HotspotBandBG,
+			want: []channelScore{{1, 0, 0}, {6, 0, 0}, {11, 0, 0}},
+		},
+		{
+			name:   "same 2.4 GHz channel",
+			band:   models.HotspotBandBG,
+			radios: []bssRadio{{channel: 6, strength: 50}, {channel: 6, strength: 0}},
+			want:   []channelScore{{1, 0, 0}, {6, 2, 2.5}, {11, 0, 0}},
+		},
+		{
+			name:   "overlapping 2.4 GHz channels",
+			band:   models.HotspotBandBG,
+			radios: []bssRadio{{channel: 3, strength: 100}, {channel: 9, strength: 50}},
+			want:   []channelScore{{1, 1, 1.2}, {6, 2, 0.8 + 0.6}, {11, 1, 0.9}},
+		},
+		{
+			name:   "2.4 GHz channels 5 apart",
+			band:   models.HotspotBandBG,
+			radios: []bssRadio{{channel: 1, strength: 100}, {channel: 11, strength: 100}},
+			want:   []channelScore{{1, 1, 2}, {6, 0, 0}, {11, 1, 2}},
+		},
+		{
+			name:   "adjacent 5 GHz channels",
+			band:   models.HotspotBandA,
+			radios: []bssRadio{{channel: 40, strength: 0}, {channel: 38, strength: 100}, {channel: 44, strength: 100}},
+			want:   []channelScore{{36, 0, 0}, {40, 1, 1}, {44, 1, 2}, {48, 0, 0}},
+		},
+		{
+			name:   "DFS channels",
+			band:   models.HotspotBandA,
+			radios: []bssRadio{{channel: 52, strength: 100}, {channel: 100, strength: 100}, {channel: 149, strength: 100}},
+			want:   []channelScore{{36, 0, 0}, {40, 0, 0}, {44, 0, 0}, {48, 0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := scoreChannels(tt.band, autoChannels[tt.band], tt.radios)
+			if len(scores) != len(tt.want) {
+				t.Fatalf("scores %v, want %v", scores, tt.want)
+			}
+			for i, score := range scores {
+				want := tt.want[i]
+				if score.channel != want.channel || score.bss != want.bss || math.Abs(score.score-want.score) > 1e-9 {
+					t.Errorf("score %d is %s, want %s", i, score, want)
+				}
+			}
+		})
+	}
+}
+
+// testAccessPoint returns an open access point on the channel, every one a BSSID of the same SSID
+func testAccessPoint(channel uint32, strength uint8) *fake.AccessPoint {
+	ap := fake.OpenAccessPoint("nearby", strength)
+	if channel > 14 {
+		ap.Frequency = 5000 + 5*channel
+	} else {
+		ap.Frequency = 2407 + 5*channel
+	}
+	return ap
+}
+
+func TestLeastCongestedChannel(t *testing.T) {
+	tests := []struct {
+		name string
+		band string
+		scan []*fake.AccessPoint
+		want uint32
+	}{
+		{
+			name: "empty 2.4 GHz band picks the lowest channel",
+			band: models.HotspotBandBG,
+			want: 1,
+		},
+		{
+			name: "tie between 1 and 11",
+			band: models.HotspotBandBG,
+			scan: []*fake.AccessPoint{testAccessPoint(6, 90)},
+			want: 1,
+		},
+		{
+			name: "tie between 6 and 11",
+			band: models.HotspotBandBG,
+			scan: []*fake.AccessPoint{testAccessPoint(1, 10), testAccessPoint(1, 60)},
+			want: 6,
+		},
+		{
+			name: "weaker neighbours preferred",
+			band: models.HotspotBandBG,
+			scan: []*fake.AccessPoint{testAccessPoint(1, 90), testAccessPoint(6, 80), testAccessPoint(11, 20)},
+			want: 11,
+		},
+		{
+			name: "5 GHz networks ignored in the 2.4 GHz band",
+			band: models.HotspotBandBG,
+			scan: []*fake.AccessPoint{testAccessPoint(36, 90), testAccessPoint(6, 90), testAccessPoint(11, 90)},
+			want: 1,
+		},
+		{
+			name: "empty 5 GHz band picks the lowest channel",
+			band: models.HotspotBandA,
+			scan: []*fake.AccessPoint{testAccessPoint(1, 90)},
+			want: 36,
+		},
+		{
+			name: "DFS networks ignored",
+			band: models.HotspotBandA,
+			scan: []*fake.AccessPoint{testAccessPoint(36, 50), testAccessPoint(52, 90), testAccessPoint(56, 90), testAccessPoint(100, 90)},
+			want: 40,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			nm.AddWifiDevice("wlan0", tt.scan)
+			c := newTestNetwork(t, nm, testConfig())
+			aps, err := c.readAccessPoints()
+			if err != nil {
+				t.Fatalf("readAccessPoints: %s", err.Error())
+			}
+			c.updateAccessPoints(aps)
+			if got := c.leastCongestedChannel(tt.band); got != tt.want {
+				t.Errorf("channel %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateHotSpotAutoChannel(t *testing.T) {
+	defer func(timeout int) { activationTimeout = timeout }(activationTimeout)
+	activationTimeout = 1
+
+	nm := fake.NewNetworkManager()
+	nm.StepInterval = testStepInterval
+	// the channel comes from the scan read when creating the hotspot, not from a later one
+	nm.AddWifiDevice("wlan0",
+		[]*fake.AccessPoint{testAccessPoint(1, 90), testAccessPoint(6, 90)},
+		[]*fake.AccessPoint{testAccessPoint(11, 90)},
+	)
+	cfg := testConfig()
+	cfg.Channel = "auto"
+	c := newTestNetwork(t, nm, cfg)
+	err := c.CreateHotSpot()
+	if err != nil {
+		t.Fatalf("CreateHotSpot: %s", err.Error())
+	}
+	activations := apActivations(nm)
+	if len(activations) != 1 {
+		t.Fatalf("%d access point activations, want 1", len(activations))
+	}
+	if channel := activations[0].Connection.Settings["802-11-wireless"]["channel"]; channel != uint32(11) {
+		t.Errorf("channel %v, want 11", channel)
+	}
+}
@@ -0,0 +1,76 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// clientPollInterval is how often the leases are read to log the clients joining and leaving the portal
+const clientPollInterval = 5 * time.Second
+
+// GetPortalClients returns the clients holding a lease on the portal network
+func (c *Config) GetPortalClients() (clients []models.Lease, err error) {
+	c.hotSpotMu.Lock()
+	created := c.isHotSpotCreated
+	c.hotSpotMu.Unlock()
+	if !created {
+		err = models.ErrHotSpotNotActive
+		return
+	}
+	return c.portalLeases()
+}
+
+// portalLeases returns the leases of the DHCP server of the portal, dnsmasq or the built-in one
+func (c *Config) portalLeases() (leases []models.Lease, err error) {
+	if c.Cfg.Fetch().IsBuiltinDHCPServer() {
+		return c.PortalServer.GetLeases(), nil
+	}
+	leases, err = c.CMD.GetDnsmasqLeases()
+	if err != nil {
+		err = fmt.Errorf("found error on GetDnsmasqLeases [%s]", err.Error())
+	}
+	return
+}
+
+// watchPortalClients logs the clients joining and leaving the portal until the context is done
+func (c *Config) watchPortalClients(ctx context.Context) {
+	known := make(map[string]models.Lease)
+	ticker := time.NewTicker(clientPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		leases, err := c.portalLeases()
+		if err != nil {
+			c.Log.Warn(fmt.Sprintf("watchPortalClients - %s", err.Error()))
+			continue
+		}
+		current := make(map[string]models.Lease)
+		for _, lease := range leases {
+			current[lease.MAC] = lease
+			if previous, ok := known[lease.MAC]; !ok || previous.IP != lease.IP {
+				c.Log.Info(fmt.Sprintf("portal client joined - %s, lease until %s", clientDescription(lease), lease.Expires.Format(time.RFC3339)))
+			}
+		}
+		for mac, lease := range known {
+			if _, ok := current[mac]; !ok {
+				c.Log.Info(fmt.Sprintf("portal client left - %s", clientDescription(lease)))
+			}
+		}
+		known = current
+	}
+}
+
+// clientDescription returns the MAC, IP and hostname of the client, as far as known
+func clientDescription(lease models.Lease) string {
+	if lease.Hostname == "" {
+		return fmt.Sprintf("%s %s", lease.MAC, lease.IP)
+	}
+	return fmt.Sprintf("%s %s (%s)", lease.MAC, lease.IP, lease.Hostname)
+}
@@ -0,0 +1,161 @@
+package network
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+// recordBuffer collects the settings recorded by the fake service, written from its D-Bus goroutine
+type recordBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *recordBuffer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+func (r *recordBuffer) records(t *testing.T) (records []map[string]map[string]string) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimSpace(r.buf.String()), "\n") {
+		var record map[string]map[string]string
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			t.Fatalf("invalid record %q: %s", line, err.Error())
+		}
+		records = append(records, record)
+	}
+	return
+}
+
+// startTestBus starts a private D-Bus daemon standing in for the system bus for the rest of the test binary
+func startTestBus(t *testing.T) (address string) {
+	t.Helper()
+	path, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not found")
+	}
+	cmd := exec.Command(path, "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe: %s", err.Error())
+	}
+	err = cmd.Start()
+	if err != nil {
+		t.Fatalf("starting dbus-daemon: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err = bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading the bus address: %s", err.Error())
+	}
+	address = strings.TrimSpace(address)
+	t.Setenv("DBUS_SYSTEM_BUS_ADDRESS", address)
+	return
+}
+
+// TestDBusRoundTrip runs the portal and a connection against the fake service through gonetworkmanager,
+// checking the settings maps as NetworkManager receives them on the bus
+func TestDBusRoundTrip(t *testing.T) {
+	address := startTestBus(t)
+
+	fakeNM := fake.NewNetworkManager()
+	fakeNM.StepInterval = testStepInterval
+	device := fakeNM.AddWifiDevice("wlan0", []*fake.AccessPoint{fake.WPA2AccessPoint("home", 80)})
+	device.HWAddress = "02:00:00:00:00:01"
+	svc := fake.NewService(testLogger(), fakeNM)
+	record := &recordBuffer{}
+	svc.Record = record
+	// the service has a connection of its own, as NetworkManager would
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("connecting the service: %s", err.Error())
+	}
+	defer conn.Close()
+	err = svc.Start(conn)
+	if err != nil {
+		t.Fatalf("Start: %s", err.Error())
+	}
+
+	nm, err := networkmanager.NewNetworkManager()
+	if err != nil {
+		t.Fatalf("NewNetworkManager: %s", err.Error())
+	}
+	cfg := testConfig()
+	cfg.Passphrase = "portal-secret"
+	c := newTestNetwork(t, nm, cfg)
+	err = c.CreateHotSpot()
+	if err != nil {
+		t.Fatalf("CreateHotSpot: %s", err.Error())
+	}
+	err = c.Connect(models.ConnectRequest{
+		SSID:       "home",
+		Passphrase: "home-secret",
+		IPv4: &models.IPConfig{
+			Method:    models.IPMethodManual,
+			Addresses: []string{"192.168.1.20/24"},
+			Gateway:   "192.168.1.1",
+			DNS:       []string{"192.168.1.1"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Connect: %s", err.Error())
+	}
+
+	records := record.records(t)
+	if len(records) != 2 {
+		t.Fatalf("%d activations recorded, want 2", len(records))
+	}
+	tests := []struct {
+		name    string
+		record  int
+		setting string
+		key     string
+		want    interface{}
+	}{
+		{"hotspot ssid", 0, "802-11-wireless", "ssid", []byte("WiFi Connect")},
+		{"hotspot mode", 0, "802-11-wireless", "mode", "ap"},
+		{"hotspot band", 0, "802-11-wireless", "band", models.HotspotBandBG},
+		{"hotspot autoconnect", 0, "connection", "autoconnect", false},
+		{"hotspot interface", 0, "connection", "interface-name", "wlan0"},
+		{"hotspot key management", 0, "802-11-wireless-security", "key-mgmt", "wpa-psk"},
+		{"hotspot passphrase", 0, "802-11-wireless-security", "psk", "portal-secret"},
+		{"hotspot pmf", 0, "802-11-wireless-security", "pmf", models.PMFDisable},
+		{"hotspot ipv4 method", 0, "ipv4", "method", "manual"},
+		{"hotspot ipv4 address", 0, "ipv4", "address-data", []map[string]dbus.Variant{{"address": dbus.MakeVariant("192.168.42.1"), "prefix": dbus.MakeVariant(uint32(24))}}},
+		{"hotspot ipv6 method", 0, "ipv6", "method", "ignore"},
+		{"station security", 1, "802-11-wireless", "security", "802-11-wireless-security"},
+		{"station key management", 1, "802-11-wireless-security", "key-mgmt", "wpa-psk"},
+		{"station passphrase", 1, "802-11-wireless-security", "psk", "home-secret"},
+		{"station ipv4 method", 1, "ipv4", "method", "manual"},
+		{"station ipv4 address", 1, "ipv4", "address-data", []map[string]dbus.Variant{{"address": dbus.MakeVariant("192.168.1.20"), "prefix": dbus.MakeVariant(uint32(24))}}},
+		{"station ipv4 gateway", 1, "ipv4", "gateway", "192.168.1.1"},
+		{"station ipv4 dns", 1, "ipv4", "dns", []uint32{inAddr("192.168.1.1")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := records[tt.record][tt.setting][tt.key]
+			want := dbus.MakeVariant(tt.want).String()
+			if !ok || got != want {
+				t.Errorf("%s.%s is %q, want %q", tt.setting, tt.key, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package network
+
+import (
+	"encoding/binary"
+	"net"
+	"unsafe"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// hostByteOrder is the byte order of the host, NetworkManager reads the IPv4 DNS servers as in_addr values
+var hostByteOrder binary.ByteOrder = binary.BigEndian
+
+func init() {
+	one := uint16(1)
+	if *(*byte)(unsafe.Pointer(&one)) == 1 {
+		hostByteOrder = binary.LittleEndian
+	}
+}
+
+// getIPSettings maps the IP configuration of the request onto the ipv4 and ipv6 settings,
+// NetworkManager defaults apply to the families left out of the request
+func getIPSettings(req models.ConnectRequest) map[string]map[string]interface{} {
+	settings := make(map[string]map[string]interface{})
+	if req.IPv4 != nil {
+		settings["ipv4"] = getIPSetting(*req.IPv4, false)
+	}
+	if req.IPv6 != nil {
+		settings["ipv6"] = getIPSetting(*req.IPv6, true)
+	}
+	return settings
+}
+
+// getIPSetting returns the ipv4 or ipv6 setting of a validated configuration
+func getIPSetting(cfg models.IPConfig, ipv6 bool) map[string]interface{} {
+	setting := make(map[string]interface{})
+	setting["method"] = cfg.EffectiveMethod()
+	if len(cfg.Addresses) > 0 {
+		addressData := make([]map[string]interface{}, 0, len(cfg.Addresses))
+		for _, address := range cfg.Addresses {
+			ip, subnet, _ := net.ParseCIDR(address)
+			prefix, _ := subnet.Mask.Size()
+			addressData = append(addressData, map[string]interface{}{
+				"address": ip.String(),
+				"prefix":  uint32(prefix),
+			})
+		}
+		setting["address-data"] = addressData
+	}
+	if cfg.Gateway != "" {
+		setting["gateway"] = cfg.Gateway
+	}
+	if len(cfg.DNS) > 0 {
+		if ipv6 {
+			dns := make([][]byte, 0, len(cfg.DNS))
+			for _, server := range cfg.DNS {
+				dns = append(dns, []byte(net.ParseIP(server).To16()))
+			}
+			setting["dns"] = dns
+		} else {
+			dns := make([]uint32, 0, len(cfg.DNS))
+			for _, server := range cfg.DNS {
+				dns = append(dns, hostByteOrder.Uint32(net.ParseIP(server).To4()))
+			}
+			setting["dns"] = dns
+		}
+		if cfg.EffectiveMethod() == models.IPMethodAuto {
+			// the servers given by DHCP or router advertisements would be used first otherwise
+			setting["ignore-auto-dns"] = true
+		}
+	}
+	if len(cfg.DNSSearch) > 0 {
+		setting["dns-search"] = cfg.DNSSearch
+	}
+	return setting
+}
@@ -0,0 +1,57 @@
+package network
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"unsafe"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// inAddr returns the uint32 NetworkManager reads as the in_addr of the IPv4 address, the bytes in memory in network order
+func inAddr(address string) uint32 {
+	var b [4]byte
+	copy(b[:], net.ParseIP(address).To4())
+	return *(*uint32)(unsafe.Pointer(&b))
+}
+
+func TestGetIPSetting(t *testing.T) {
+	setting := getIPSetting(models.IPConfig{
+		Method:    models.IPMethodManual,
+		Addresses: []string{"192.168.1.20/24", "10.0.0.5/8"},
+		Gateway:   "192.168.1.1",
+		DNS:       []string{"192.168.1.1", "9.9.9.9"},
+		DNSSearch: []string{"example.com"},
+	}, false)
+	if setting["method"] != models.IPMethodManual || setting["gateway"] != "192.168.1.1" {
+		t.Errorf("setting %v, want method manual and gateway 192.168.1.1", setting)
+	}
+	addressData, _ := setting["address-data"].([]map[string]interface{})
+	if len(addressData) != 2 || addressData[0]["address"] != "192.168.1.20" || addressData[0]["prefix"] != uint32(24) ||
+		addressData[1]["address"] != "10.0.0.5" || addressData[1]["prefix"] != uint32(8) {
+		t.Errorf("address-data %v", setting["address-data"])
+	}
+	dns, _ := setting["dns"].([]uint32)
+	if len(dns) != 2 || dns[0] != inAddr("192.168.1.1") || dns[1] != inAddr("9.9.9.9") {
+		t.Errorf("dns %v, want the in_addr values %d and %d", setting["dns"], inAddr("192.168.1.1"), inAddr("9.9.9.9"))
+	}
+	if _, ok := setting["ignore-auto-dns"]; ok {
+		t.Errorf("ignore-auto-dns set with method manual")
+	}
+	if search, _ := setting["dns-search"].([]string); len(search) != 1 || search[0] != "example.com" {
+		t.Errorf("dns-search %v", setting["dns-search"])
+	}
+
+	setting = getIPSetting(models.IPConfig{DNS: []string{"2001:4860:4860::8888"}}, true)
+	if setting["method"] != models.IPMethodAuto || setting["ignore-auto-dns"] != true {
+		t.Errorf("setting %v, want method auto ignoring the servers of the network", setting)
+	}
+	dns6, _ := setting["dns"].([][]byte)
+	if len(dns6) != 1 || !bytes.Equal(dns6[0], net.ParseIP("2001:4860:4860::8888")) {
+		t.Errorf("dns %v, want the 16 bytes of 2001:4860:4860::8888", setting["dns"])
+	}
+	if _, ok := setting["address-data"]; ok {
+		t.Errorf("address-data set without addresses")
+	}
+}
@@ -1,13 +1,16 @@
 package network
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Wifx/gonetworkmanager"
-	"github.com/godbus/dbus/v5"
 	"github.com/sirupsen/logrus"
 	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
 	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
@@ -16,55 +19,131 @@ import (
 // Config represent this module
 type Config struct {
 	Log               *logrus.Logger
-	NetworkManager    gonetworkmanager.NetworkManager
+	NetworkManager    interfaces.NetworkManager
 	CMD               interfaces.Command
-	WifiDevice        gonetworkmanager.DeviceWireless
-	HotSpotConnection gonetworkmanager.ActiveConnection
+	PortalServer      interfaces.PortalServer
+	WifiDevice        interfaces.WifiDevice
+	StationDevice     interfaces.WifiDevice
+	HotSpotConnection interfaces.ActiveConnection
+	HotSpotSSID       string
+	HotSpotPassphrase string
 	isHotSpotCreated  bool
 	Cfg               models.ConfigHandler
 	WifiInterface     string
+	StationInterface  string
 	AccessPoints      []AccessPoint
+	ScannedAt         time.Time
 	HTTPServer        interfaces.HTTPServer
+	stopClientWatch   context.CancelFunc
+	// hotSpotMu guards the hotspot state, it is taken before apMu
+	hotSpotMu sync.Mutex
+	apMu      sync.Mutex
 }
 
+var errNoAccessPoint = errors.New("no accesspoint found")
+
+// hotSpotDeactivationTimeout bounds the wait for the access point to go down when closing the portal
+const hotSpotDeactivationTimeout = 10 * time.Second
+
+// activationTimeout bounds the wait for a connection to be activated, in seconds
+var activationTimeout = 20
+
+// connectivityTimeout bounds the wait for Internet access once connected, in seconds
+var connectivityTimeout = 20
+
+// scanRetryInterval is the delay between two reads of an empty scan
+var scanRetryInterval = 2 * time.Second
+
 // AccessPoint represents Access point
 type AccessPoint struct {
-	SSID     string
-	Path     dbus.ObjectPath
-	Strength uint8
-	Security models.SECURITY
+	SSID       string
+	AP         interfaces.AccessPoint
+	Strength   uint8
+	Security   models.SECURITY
+	Frequency  uint32
+	MaxBitrate uint32
+	Bands      []string
+	BSSIDCount int
+	Saved      bool
+	LastSeen   time.Time
+	// radios holds every BSSID of the SSID, the portal channel is picked from them
+	radios []bssRadio
 }
 
 // NewNetwork returns access to this module
-func NewNetwork(l *logrus.Logger, cmd interfaces.Command, cfg models.ConfigHandler) (*Config, error) {
-	nm, err := gonetworkmanager.NewNetworkManager()
+func NewNetwork(l *logrus.Logger, cmd interfaces.Command, ps interfaces.PortalServer, nm interfaces.NetworkManager, cfg models.ConfigHandler) (*Config, error) {
+	wDevice, sDevice, err := getWifiDevices(nm, cfg.Fetch().Interface, cfg.Fetch().StationInterface)
 	if err != nil {
-		err = fmt.Errorf("found error on NewNetworkManager [%s]", err.Error())
 		return nil, err
 	}
 
-	wDevice, err := getWifiDevice(nm)
+	var dInterface string
+	dInterface, err = wDevice.GetPropertyInterface()
 	if err != nil {
 		return nil, err
 	}
-
-	var dInterface string
-	dInterface, err = wDevice.GetPropertyInterface()
+	var sInterface string
+	sInterface, err = sDevice.GetPropertyInterface()
 	if err != nil {
 		return nil, err
 	}
 
 	l.Info(fmt.Sprintf("device interface : %s", dInterface))
+	if sInterface != dInterface {
+		l.Info(fmt.Sprintf("station interface : %s (dual-radio mode)", sInterface))
+	}
 	return &Config{
-		Log:            l,
-		Cfg:            cfg,
-		CMD:            cmd,
-		NetworkManager: nm,
-		WifiDevice:     wDevice,
-		WifiInterface:  dInterface,
+		Log:              l,
+		Cfg:              cfg,
+		CMD:              cmd,
+		PortalServer:     ps,
+		NetworkManager:   nm,
+		WifiDevice:       wDevice,
+		WifiInterface:    dInterface,
+		StationDevice:    sDevice,
+		StationInterface: sInterface,
 	}, nil
 }
 
+// startPortalServer starts the DHCP and DNS server of the portal, dnsmasq or the built-in one,
+// and the logging of its clients
+func (c *Config) startPortalServer(subnet models.PortalSubnet) {
+	var ctx context.Context
+	ctx, c.stopClientWatch = context.WithCancel(context.Background())
+	go c.watchPortalClients(ctx)
+	if !c.Cfg.Fetch().IsBuiltinDHCPServer() {
+		c.CMD.StartDnsmasq(c.WifiInterface, subnet)
+		return
+	}
+	err := c.PortalServer.Start(c.WifiInterface, subnet)
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("startPortalServer - found error on Start [%s]", err.Error()))
+	}
+}
+
+// stopPortalServer stops the DHCP and DNS server of the portal
+func (c *Config) stopPortalServer() {
+	if c.stopClientWatch != nil {
+		c.stopClientWatch()
+		c.stopClientWatch = nil
+	}
+	if !c.Cfg.Fetch().IsBuiltinDHCPServer() {
+		c.CMD.KillDNSMasq()
+		return
+	}
+	c.PortalServer.Stop()
+}
+
+// GetProcessStatus returns the status of the helper processes of the portal
+func (c *Config) GetProcessStatus() (status []models.ProcessStatus) {
+	return c.CMD.GetProcessStatus()
+}
+
+// isDualRadio tells whether the station connection is made on another device than the portal
+func (c *Config) isDualRadio() bool {
+	return c.StationDevice != c.WifiDevice
+}
+
 // StartPortal used to start wifi-connect captive portal
 func (c *Config) StartPortal() {
 	c.Log.Info("starting wifi connect captive portal")
@@ -87,12 +166,24 @@ func (c *Config) ClosePortal() {
 
 // GetAccessPoint method used to get access point for the captive portal
 func (c *Config) GetAccessPoint() (accessPoints []models.AccessPoint, err error) {
+	c.apMu.Lock()
+	defer c.apMu.Unlock()
 	if len(c.AccessPoints) > 0 {
 		accessPoints = make([]models.AccessPoint, len(c.AccessPoints))
 		for i, Ap := range c.AccessPoints {
 			accessPoints[i] = models.AccessPoint{
-				SSID:     Ap.SSID,
-				Security: Ap.Security.String(),
+				SSID:       Ap.SSID,
+				Security:   Ap.Security.String(),
+				Strength:   Ap.Strength,
+				SignalDBm:  models.StrengthToDBm(Ap.Strength),
+				Frequency:  Ap.Frequency,
+				Band:       models.FrequencyToBand(Ap.Frequency),
+				Channel:    models.FrequencyToChannel(Ap.Frequency),
+				Bands:      Ap.Bands,
+				BSSIDCount: Ap.BSSIDCount,
+				MaxBitrate: Ap.MaxBitrate,
+				Saved:      Ap.Saved,
+				LastSeen:   Ap.LastSeen,
 			}
 		}
 	}
@@ -101,6 +192,14 @@ func (c *Config) GetAccessPoint() (accessPoints []models.AccessPoint, err error)
 
 // CreateHotSpot returns network manager info
 func (c *Config) CreateHotSpot() (err error) {
+	c.hotSpotMu.Lock()
+	defer c.hotSpotMu.Unlock()
+	return c.createHotSpot(context.Background(), true)
+}
+
+// createHotSpot creates the hotspot, the context bounds the wait for access points to show up in the scan.
+// rotate is false when the hotspot is only restored, its random passphrase is kept then. The caller holds hotSpotMu.
+func (c *Config) createHotSpot(ctx context.Context, rotate bool) (err error) {
 	cfg := c.Cfg.Fetch()
 	if c.isHotSpotCreated {
 		err = errors.New("CreateHotSpot - Hotspot already created")
@@ -108,23 +207,55 @@ func (c *Config) CreateHotSpot() (err error) {
 		return
 	}
 	c.Log.Info("CreateHotSpot - creating access point")
-	c.AccessPoints, err = c.getAccessPoint(10)
-	if err != nil {
+	var aps []AccessPoint
+	aps, err = c.getAccessPoint(ctx, 10)
+	c.updateAccessPoints(aps)
+	if err == errNoAccessPoint || (err != nil && err == ctx.Err()) {
+		// hidden networks can still be provisioned from an empty list
+		c.Log.Warn("CreateHotSpot - no access point in range, starting portal with an empty list")
+	} else if err != nil {
 		c.Log.Error(fmt.Sprintf("found error on getWirelessDevice - getAccessPoint [%s]", err.Error()))
 		return
 	}
+	var ssid string
+	ssid, err = c.hotSpotSSID()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on hotSpotSSID [%s]", err.Error()))
+		return
+	}
+	var passphrase string
+	passphrase, err = c.hotSpotPassphrase(rotate)
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on hotSpotPassphrase [%s]", err.Error()))
+		return
+	}
+	var subnet models.PortalSubnet
+	subnet, err = c.portalSubnet()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on portalSubnet [%s]", err.Error()))
+		return
+	}
+	var band string
+	var channel uint32
+	band, channel, err = c.hotSpotRadio()
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on hotSpotRadio [%s]", err.Error()))
+		return
+	}
 	connection := make(map[string]map[string]interface{})
 	wl := map[string]interface{}{
-		"ssid":     []byte(cfg.SSID),
-		"band":     "bg",
-		"hidden":   false,
-		"mode":     "ap",
-		"security": "802-11-wireless-security",
+		"ssid":   []byte(ssid),
+		"band":   band,
+		"hidden": false,
+		"mode":   "ap",
+	}
+	if channel != 0 {
+		wl["channel"] = channel
 	}
 
 	cn := map[string]interface{}{
 		"autoconnect":    false,
-		"id":             cfg.SSID,
+		"id":             ssid,
 		"interface-name": c.WifiInterface,
 		"type":           "802-11-wireless",
 	}
@@ -132,8 +263,8 @@ func (c *Config) CreateHotSpot() (err error) {
 	ipv4 := make(map[string]interface{})
 	ipv4["method"] = "manual"
 	ipv4Address := make(map[string]interface{})
-	ipv4Address["address"] = cfg.Gateway
-	ipv4Address["prefix"] = uint32(24)
+	ipv4Address["address"] = subnet.Gateway.String()
+	ipv4Address["prefix"] = uint32(subnet.Prefix())
 	ipv4AddressData := make([]map[string]interface{}, 1)
 	ipv4AddressData[0] = ipv4Address
 	ipv4["address-data"] = ipv4AddressData
@@ -141,18 +272,34 @@ func (c *Config) CreateHotSpot() (err error) {
 	ipv6 := map[string]interface{}{
 		"method": "ignore",
 	}
+	if cfg.IPv6 {
+		// router advertisements and DHCPv6 are served by dnsmasq
+		var gateway6 net.IP
+		var prefix6 int
+		gateway6, prefix6, err = cfg.IPv6Gateway()
+		if err != nil {
+			c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on IPv6Gateway [%s]", err.Error()))
+			return
+		}
+		ipv6["method"] = "manual"
+		ipv6["address-data"] = []map[string]interface{}{
+			{
+				"address": gateway6.String(),
+				"prefix":  uint32(prefix6),
+			},
+		}
+	}
 
-	security := map[string]interface{}{
-		"key-mgmt": "wpa-psk",
-		"psk":      cfg.Passphrase,
+	if security := getHotSpotSecuritySettings(cfg.PortalSecurity(), passphrase); security != nil {
+		wl["security"] = "802-11-wireless-security"
+		connection["802-11-wireless-security"] = security
 	}
 
 	connection["802-11-wireless"] = wl
-	connection["802-11-wireless-security"] = security
 	connection["connection"] = cn
 	connection["ipv4"] = ipv4
 	connection["ipv6"] = ipv6
-	var hpConn gonetworkmanager.ActiveConnection
+	var hpConn interfaces.ActiveConnection
 	hpConn, err = c.NetworkManager.AddAndActivateConnection(connection, c.WifiDevice)
 	if err != nil {
 		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on AddAndActivateConnection - %s", err.Error()))
@@ -161,162 +308,304 @@ func (c *Config) CreateHotSpot() (err error) {
 		return
 	}
 
-	var isActivated bool
-	isActivated, err = c.waitForConnectionState(20, hpConn, gonetworkmanager.NmActiveConnectionStateActivated)
-	if err != nil {
+	err = c.waitForConnectionState(activationTimeout, c.WifiDevice, hpConn, gonetworkmanager.NmActiveConnectionStateActivated)
+	if err == nil {
+		c.Log.Info(fmt.Sprintf("CreateHotSpot - Access point created - %s\n", ssid))
+		c.HotSpotSSID = ssid
+		c.isHotSpotCreated = true
+		c.startPortalServer(subnet)
+		c.HotSpotConnection = hpConn
+		err = c.writeHotSpotQRCode()
+		if err != nil {
+			// the portal works without the file, it can still be shown from the web server
+			c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on writeHotSpotQRCode [%s]", err.Error()))
+			err = nil
+		}
+		return
+	}
+	if _, ok := err.(*models.ConnectError); !ok {
 		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on waitForConnectionState: %s", err.Error()))
 		c.isHotSpotCreated = false
 		c.HotSpotConnection = nil
 		return
 	}
-	if isActivated {
-		c.Log.Info(fmt.Sprintf("CreateHotSpot - Access point created - %s\n", cfg.SSID))
-		c.isHotSpotCreated = true
-		c.CMD.StartDnsmasq(c.WifiInterface)
-		c.HotSpotConnection = hpConn
-		return
-	}
 
-	err = errors.New("CreateHotSpot - connection could not be activated, closing connection")
-	c.Log.Error(err.Error())
-	var conn gonetworkmanager.Connection
-	conn, err = hpConn.GetPropertyConnection()
-	if err != nil {
-		err = fmt.Errorf("CreateHotSpot - found error on GetPropertyConnection [%s]", err.Error())
-		c.Log.Error(err.Error())
+	// the activation error is returned, failing to clean up is only logged
+	c.Log.Error(fmt.Sprintf("CreateHotSpot - connection could not be activated, closing connection [%s]", err.Error()))
+	c.isHotSpotCreated = false
+	c.HotSpotConnection = nil
+	conn, connErr := hpConn.GetPropertyConnection()
+	if connErr != nil {
+		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on GetPropertyConnection [%s]", connErr.Error()))
 		return
 	}
-	err = c.NetworkManager.DeactivateConnection(hpConn)
-	if err != nil {
-		err = fmt.Errorf("CreateHotSpot - found error on DeactivateConnection [%s]", err.Error())
-		c.Log.Error(err.Error())
-		return
+	deactivateErr := c.NetworkManager.DeactivateConnection(hpConn)
+	if deactivateErr != nil {
+		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on DeactivateConnection [%s]", deactivateErr.Error()))
 	}
-	err = conn.Delete()
-	if err != nil {
-		err = fmt.Errorf("CreateHotSpot - found error on Delete [%s]", err.Error())
-		c.Log.Error(err.Error())
-		return
+	deleteErr := conn.Delete()
+	if deleteErr != nil {
+		c.Log.Error(fmt.Sprintf("CreateHotSpot - found error on Delete [%s]", deleteErr.Error()))
 	}
-	c.isHotSpotCreated = false
-	c.HotSpotConnection = nil
 	return
 }
 
 // CloseHotSpot used to close hotspot connection
 func (c *Config) CloseHotSpot() (err error) {
+	c.hotSpotMu.Lock()
+	defer c.hotSpotMu.Unlock()
+	return c.closeHotSpot()
+}
+
+// closeHotSpot closes the hotspot, the caller holds hotSpotMu
+func (c *Config) closeHotSpot() (err error) {
 	if c.isHotSpotCreated {
 		c.Log.Info("Close access point")
-		var conn gonetworkmanager.Connection
+		var conn interfaces.Connection
 		conn, err = c.HotSpotConnection.GetPropertyConnection()
 		if err != nil {
 			err = fmt.Errorf("CloseHotSpot - found error on GetPropertyConnection [%s]", err.Error())
 			c.Log.Error(err.Error())
 			return
 		}
+		ctx, cancel := context.WithTimeout(context.Background(), hotSpotDeactivationTimeout)
+		defer cancel()
+		var changes <-chan interfaces.ActiveConnectionStateChange
+		changes, err = c.HotSpotConnection.SubscribeState(ctx)
+		if err != nil {
+			err = fmt.Errorf("CloseHotSpot - found error on SubscribeState [%s]", err.Error())
+			c.Log.Error(err.Error())
+			return
+		}
 		err = c.NetworkManager.DeactivateConnection(c.HotSpotConnection)
 		if err != nil {
 			err = fmt.Errorf("CloseHotSpot - found error on DeactivateConnection [%s]", err.Error())
 			c.Log.Error(err.Error())
 			return
 		}
+		if !c.waitForDeactivation(changes) {
+			c.Log.Warn(fmt.Sprintf("CloseHotSpot - access point not deactivated after %s", hotSpotDeactivationTimeout))
+		}
 		err = conn.Delete()
 		if err != nil {
 			err = fmt.Errorf("CloseHotSpot - found error on Delete [%s]", err.Error())
 			c.Log.Error(err.Error())
 			return
 		}
-		c.CMD.KillDNSMasq()
+		c.stopPortalServer()
 		c.isHotSpotCreated = false
 		c.HotSpotConnection = nil
-		time.Sleep(5 * time.Second)
 	}
 	return
 }
 
-// Connect method used to connect to the network by captive portal
-func (c *Config) Connect(ssid string, pwd string, identity string) (flg bool) {
-	var err error
-	flg = false
-	err = c.deleteConnectionIfSameNetworkExists(ssid)
+// ValidateConnectRequest checks the credentials of the request against the security of the network found in the last scan
+func (c *Config) ValidateConnectRequest(req models.ConnectRequest) (err error) {
+	err = req.ValidateIP()
 	if err != nil {
-		c.Log.Error(err.Error())
 		return
 	}
-	c.ClosePortal()
-	c.Log.Info(fmt.Sprintf("connecting access point ---> %s", ssid))
-	var ap gonetworkmanager.AccessPoint
-	ap, err = c.getAccessPointFromSSID(ssid)
-	if err != nil {
-		c.Log.Error(err.Error())
-		return
+	if req.Hidden {
+		if req.Security == "" {
+			return errors.New("security is required for hidden networks")
+		}
+		var security models.SECURITY
+		security, err = models.ParseSecurity(req.Security)
+		if err != nil {
+			return
+		}
+		return validateCredentials(security, req)
+	}
+	c.apMu.Lock()
+	defer c.apMu.Unlock()
+	for _, ap := range c.AccessPoints {
+		if ap.SSID == req.SSID {
+			return validateCredentials(ap.Security, req)
+		}
 	}
+	return
+}
 
-	connection := make(map[string]map[string]interface{})
-	connection["802-11-wireless"] = make(map[string]interface{})
-	connection["802-11-wireless"]["security"] = "802-11-wireless-security"
-	var cred map[string]map[string]interface{}
-	cred, err = getWirelessCredentials(ap, pwd, identity)
-	for k, val := range cred {
-		connection[k] = val
+// Connect method used to connect to the network by captive portal.
+// Failures are returned as *models.ConnectError, the portal is restarted after a failed activation.
+// In dual-radio mode the portal stays up during the attempt and is left for the caller to close,
+// a network giving no Internet access is a failure there as the client can still pick another one.
+func (c *Config) Connect(req models.ConnectRequest) (err error) {
+	ssid := req.SSID
+	err = c.ValidateConnectRequest(req)
+	if err != nil {
+		c.Log.Error(fmt.Sprintf("Connect - invalid request: %s", err.Error()))
+		err = models.NewConnectError(models.ConnectErrorInvalidRequest, "%s", err.Error())
+		return
 	}
-	var wifiConn gonetworkmanager.ActiveConnection
-	wifiConn, err = c.NetworkManager.AddAndActivateWirelessConnection(connection, c.WifiDevice, ap)
+	err = c.deleteConnectionIfSameNetworkExists(ssid)
 	if err != nil {
-		c.Log.Error(fmt.Sprintf("found error on AddAndActivateWirelessConnection: %s", err.Error()))
+		c.Log.Error(err.Error())
+		err = toConnectError(err)
 		return
 	}
-	var isActivated bool
-	isActivated, err = c.waitForConnectionState(20, wifiConn, gonetworkmanager.NmActiveConnectionStateActivated)
+	dualRadio := c.isDualRadio()
+	if !dualRadio {
+		c.ClosePortal()
+	}
+	c.Log.Info(fmt.Sprintf("connecting access point ---> %s", ssid))
+	var wifiConn interfaces.ActiveConnection
+	wifiConn, err = c.activateConnection(req)
 	if err != nil {
-		c.Log.Error(fmt.Sprintf("found error on waitForConnectionState: %s", err.Error()))
+		c.Log.Error(err.Error())
+		if !dualRadio {
+			c.StartPortal()
+		}
+		err = toConnectError(err)
 		return
 	}
-	if isActivated {
+	// the active connection object goes away with a failed activation, keep hold of its profile
+	conn, connErr := wifiConn.GetPropertyConnection()
+	if connErr != nil {
+		c.Log.Error(fmt.Sprintf("found error on GetPropertyConnection of new created connection: %s", connErr.Error()))
+	}
+	err = c.waitForConnectionState(activationTimeout, c.StationDevice, wifiConn, gonetworkmanager.NmActiveConnectionStateActivated)
+	if err == nil {
 		var cFLag bool
-		var connErr error
-		cFLag, connErr = c.waitForConnectivity(20)
+		cFLag, connErr = c.waitForConnectivity(connectivityTimeout)
 		if connErr != nil {
-			c.Log.Warn(fmt.Sprintf("Getting Internet connectivity failed: %s", err.Error()))
+			c.Log.Warn(fmt.Sprintf("Getting Internet connectivity failed: %s", connErr.Error()))
 		}
 		if cFLag {
 			c.Log.Info("Internet connectivity established")
-		} else {
-			c.Log.Warn("Cannot establish Internet connectivity")
+			return
+		}
+		c.Log.Warn("Cannot establish Internet connectivity")
+		if !dualRadio {
+			return
+		}
+		err = models.NewConnectError(models.ConnectErrorNoConnectivity, "no connectivity after %ds", connectivityTimeout)
+	}
+	err = toConnectError(err)
+	c.Log.Warn(fmt.Sprintf("Connection to access point failed %s: %s", ssid, err.Error()))
+	if conn != nil {
+		connErr = conn.Delete()
+		if connErr != nil {
+			c.Log.Error(fmt.Sprintf("found error on deleting connection object: %s", connErr.Error()))
+		}
+	}
+	if !dualRadio {
+		c.StartPortal()
+	}
+	return
+}
+
+// toConnectError returns err as a *models.ConnectError, errors without a reason are internal errors
+func toConnectError(err error) *models.ConnectError {
+	var connectErr *models.ConnectError
+	if errors.As(err, &connectErr) {
+		return connectErr
+	}
+	return models.NewConnectError(models.ConnectErrorInternal, "%s", err.Error())
+}
+
+func (c *Config) activateConnection(req models.ConnectRequest) (wifiConn interfaces.ActiveConnection, err error) {
+	connection := make(map[string]map[string]interface{})
+	connection["802-11-wireless"] = make(map[string]interface{})
+	var cred map[string]map[string]interface{}
+	if req.Hidden {
+		// hidden networks are not part of the scan, the profile is built from the request alone
+		var security models.SECURITY
+		security, err = models.ParseSecurity(req.Security)
+		if err != nil {
+			return
+		}
+		cred, err = getSecuritySettings(security, req)
+		if err != nil {
+			err = fmt.Errorf("found error on getSecuritySettings: %s", err.Error())
+			return
+		}
+		connection["connection"] = map[string]interface{}{
+			"id":   req.SSID,
+			"type": "802-11-wireless",
+		}
+		connection["802-11-wireless"]["ssid"] = []byte(req.SSID)
+		connection["802-11-wireless"]["mode"] = "infrastructure"
+		connection["802-11-wireless"]["hidden"] = true
+		if _, ok := cred["802-11-wireless-security"]; ok {
+			connection["802-11-wireless"]["security"] = "802-11-wireless-security"
+		}
+		for k, val := range cred {
+			connection[k] = val
+		}
+		for k, val := range getIPSettings(req) {
+			connection[k] = val
+		}
+		wifiConn, err = c.NetworkManager.AddAndActivateConnection(connection, c.StationDevice)
+		if err != nil {
+			err = fmt.Errorf("found error on AddAndActivateConnection: %s", err.Error())
 		}
-		flg = true
 		return
 	}
-	var conn gonetworkmanager.Connection
-	conn, err = wifiConn.GetPropertyConnection()
+
+	var ap interfaces.AccessPoint
+	ap, err = c.getAccessPointFromSSID(req.SSID)
 	if err != nil {
-		c.Log.Error(fmt.Sprintf("found error on GetPropertyConnection of new created connection: %s", err.Error()))
 		return
 	}
-	err = conn.Delete()
+	connection["802-11-wireless"]["security"] = "802-11-wireless-security"
+	cred, err = getWirelessCredentials(ap, req)
 	if err != nil {
-		c.Log.Error(fmt.Sprintf("found error on deleting connection object: %s", err.Error()))
+		err = fmt.Errorf("found error on getWirelessCredentials: %s", err.Error())
 		return
 	}
-	c.Log.Warn(fmt.Sprintf("Connection to access point not activated %s", ssid))
-	c.StartPortal()
+	for k, val := range cred {
+		connection[k] = val
+	}
+	for k, val := range getIPSettings(req) {
+		connection[k] = val
+	}
+	wifiConn, err = c.NetworkManager.AddAndActivateWirelessConnection(connection, c.StationDevice, ap)
+	if err != nil {
+		err = fmt.Errorf("found error on AddAndActivateWirelessConnection: %s", err.Error())
+	}
 	return
 }
 
-func (c *Config) getAccessPoint(retryLimit int) (ap []AccessPoint, err error) {
-	try := 0
-start:
-	var activeAPoints []gonetworkmanager.AccessPoint
-	activeAPoints, err = c.WifiDevice.GetAccessPoints()
+// getAccessPoint reads the scan results, retrying up to retryLimit times while none are visible.
+// It returns errNoAccessPoint once the retries are exhausted, or the context error when cancelled.
+func (c *Config) getAccessPoint(ctx context.Context, retryLimit int) (ap []AccessPoint, err error) {
+	for try := 0; ; try++ {
+		ap, err = c.readAccessPoints()
+		if err != nil || len(ap) > 0 {
+			return
+		}
+		if try >= retryLimit {
+			err = errNoAccessPoint
+			return
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(scanRetryInterval):
+		}
+	}
+}
+
+// readAccessPoints returns the access points currently known to the device, one per SSID, strongest first
+func (c *Config) readAccessPoints() (ap []AccessPoint, err error) {
+	var activeAPoints []interfaces.AccessPoint
+	activeAPoints, err = c.StationDevice.GetAccessPoints()
 	if err != nil {
 		return
 	}
 	var loopErr error
+	var saved map[string]bool
+	saved, loopErr = c.getSavedSSIDs()
+	if loopErr != nil {
+		c.Log.Error(fmt.Sprintf("getAccessPoint - found error on getSavedSSIDs - %s", loopErr.Error()))
+	}
 	var tempAP = make(map[string]AccessPoint)
 	for _, aPoint := range activeAPoints {
-		path := aPoint.GetPath()
 		var ssid string
 		var strength uint8
+		var frequency, maxBitrate uint32
 		ssid, loopErr = aPoint.GetPropertySSID()
 		if loopErr != nil {
 			c.Log.Error(fmt.Sprintf("getAccessPoint - found error on GetPropertySSID - %s", loopErr.Error()))
@@ -327,6 +616,16 @@ start:
 			c.Log.Error(fmt.Sprintf("getAccessPoint - found error on GetPropertyStrength - %s", loopErr.Error()))
 			continue
 		}
+		frequency, loopErr = aPoint.GetPropertyFrequency()
+		if loopErr != nil {
+			c.Log.Error(fmt.Sprintf("getAccessPoint - found error on GetPropertyFrequency - %s", loopErr.Error()))
+			continue
+		}
+		maxBitrate, loopErr = aPoint.GetPropertyMaxBitrate()
+		if loopErr != nil {
+			c.Log.Error(fmt.Sprintf("getAccessPoint - found error on GetPropertyMaxBitrate - %s", loopErr.Error()))
+			continue
+		}
 		var security models.SECURITY
 		security, loopErr = getAccessPointSecurity(aPoint)
 		if loopErr != nil {
@@ -335,57 +634,126 @@ start:
 		}
 		if ssid != "" {
 			a := AccessPoint{
-				SSID:     ssid,
-				Security: security,
-				Strength: strength,
-				Path:     path,
+				SSID:       ssid,
+				Security:   security,
+				Strength:   strength,
+				AP:         aPoint,
+				Frequency:  frequency,
+				MaxBitrate: maxBitrate,
+				Saved:      saved[ssid],
+			}
+			radio := newBSSRadio(frequency, strength)
+			// several BSSIDs share the SSID, keep the strongest one and aggregate the rest
+			if prev, ok := tempAP[ssid]; ok {
+				a.BSSIDCount = prev.BSSIDCount
+				a.Bands = prev.Bands
+				a.radios = prev.radios
+				if prev.MaxBitrate > a.MaxBitrate {
+					a.MaxBitrate = prev.MaxBitrate
+				}
+				if prev.Strength >= a.Strength {
+					a.AP, a.Strength, a.Security, a.Frequency = prev.AP, prev.Strength, prev.Security, prev.Frequency
+				}
+			}
+			a.BSSIDCount++
+			a.radios = append(a.radios, radio)
+			if radio.band != "" && !containsString(a.Bands, radio.band) {
+				a.Bands = append(a.Bands, radio.band)
 			}
 			tempAP[ssid] = a
 		}
 	}
+	now := time.Now()
 	for _, a := range tempAP {
+		a.LastSeen = now
 		ap = append(ap, a)
 	}
-	if len(ap) == 0 {
-		if try >= retryLimit {
-			err = errors.New("no accesspoint found")
-		}
-		try = try + 1
-		time.Sleep(2 * time.Second)
-		goto start
-	}
 	sort.Slice(ap[:], func(i, j int) bool {
 		return ap[i].Strength > ap[j].Strength
 	})
-	var printAccessPoint []string
+	return
+}
 
-	for _, a := range ap {
-		printAccessPoint = append(printAccessPoint, a.SSID)
+// getSavedSSIDs returns the SSIDs of the wireless client profiles stored by NetworkManager
+func (c *Config) getSavedSSIDs() (ssids map[string]bool, err error) {
+	ssids = make(map[string]bool)
+	conns, err := c.NetworkManager.ListConnections()
+	if err != nil {
+		err = fmt.Errorf("found error on ListConnections - %s", err.Error())
+		return
+	}
+	for _, conn := range conns {
+		var sett gonetworkmanager.ConnectionSettings
+		sett, err = conn.GetSettings()
+		if err != nil {
+			err = fmt.Errorf("found error on GetSettings - %s", err.Error())
+			return
+		}
+		if mode, _ := sett["802-11-wireless"]["mode"].(string); mode == "ap" {
+			continue
+		}
+		if ssid, ok := sett["802-11-wireless"]["ssid"].([]byte); ok {
+			ssids[string(ssid)] = true
+		}
 	}
 	return
 }
 
-func getWirelessCredentials(ap gonetworkmanager.AccessPoint, pwd string, identity string) (security80211 map[string]map[string]interface{}, err error) {
-	security80211 = make(map[string]map[string]interface{})
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func getWirelessCredentials(ap interfaces.AccessPoint, req models.ConnectRequest) (security80211 map[string]map[string]interface{}, err error) {
 	var security models.SECURITY
 	security, err = getAccessPointSecurity(ap)
 	if err != nil {
 		return
 	}
-	if (security & models.ENTERPRISE) == models.ENTERPRISE {
+	return getSecuritySettings(security, req)
+}
+
+// getSecuritySettings returns the security settings of a connection profile for the security and credentials
+func getSecuritySettings(security models.SECURITY, req models.ConnectRequest) (security80211 map[string]map[string]interface{}, err error) {
+	security80211 = make(map[string]map[string]interface{})
+	pwd := req.Passphrase
+	err = validateCredentials(security, req)
+	if err != nil {
+		return
+	}
+	if (security&models.ENTERPRISE192) == models.ENTERPRISE192 || (security&models.ENTERPRISE) == models.ENTERPRISE {
 		setting1 := make(map[string]interface{})
-		setting2 := make(map[string]interface{})
 		setting1["key-mgmt"] = "wpa-eap"
-		setting2["eap"] = []string{"peap"}
-		setting2["identity"] = identity
-		setting2["password"] = pwd
-		setting2["phase2-auth"] = "mschapv2"
+		if (security & models.ENTERPRISE192) == models.ENTERPRISE192 {
+			setting1["key-mgmt"] = "wpa-eap-suite-b-192"
+			setting1["pmf"] = models.PMFRequired
+		}
+		security80211["802-11-wireless-security"] = setting1
+		security80211["802-1x"] = get8021xSetting(req)
+	} else if (security&models.SAE) == models.SAE && (security&models.WPA2) != models.WPA2 {
+		// WPA3-only network
+		setting1 := make(map[string]interface{})
+		setting1["key-mgmt"] = "sae"
+		setting1["psk"] = pwd
+		setting1["pmf"] = models.PMFRequired
 		security80211["802-11-wireless-security"] = setting1
-		security80211["802-1x"] = setting2
 	} else if (security&models.WPA2) == models.WPA2 || (security&models.WPA) == models.WPA {
 		setting1 := make(map[string]interface{})
 		setting1["key-mgmt"] = "wpa-psk"
 		setting1["psk"] = pwd
+		if (security & models.SAE) == models.SAE {
+			// WPA2/WPA3 transition network, NetworkManager uses SAE for wpa-psk when the driver supports it
+			setting1["pmf"] = models.PMFOptional
+		}
+		security80211["802-11-wireless-security"] = setting1
+	} else if (security & models.OWE) == models.OWE {
+		setting1 := make(map[string]interface{})
+		setting1["key-mgmt"] = "owe"
+		setting1["pmf"] = models.PMFRequired
 		security80211["802-11-wireless-security"] = setting1
 	} else if (security & models.WEP) == models.WEP {
 		setting1 := make(map[string]interface{})
@@ -396,7 +764,96 @@ func getWirelessCredentials(ap gonetworkmanager.AccessPoint, pwd string, identit
 	return
 }
 
-func getAccessPointSecurity(ap gonetworkmanager.AccessPoint) (security models.SECURITY, err error) {
+// getHotSpotSecuritySettings returns the security setting of the hotspot profile, nil for an open network
+func getHotSpotSecuritySettings(security string, passphrase string) map[string]interface{} {
+	switch security {
+	case models.PortalSecurityWPA2:
+		// PMF stays off, NetworkManager would offer SAE next to WPA2 otherwise
+		return map[string]interface{}{
+			"key-mgmt": "wpa-psk",
+			"psk":      passphrase,
+			"pmf":      models.PMFDisable,
+		}
+	case models.PortalSecurityTransition:
+		// WPA2 clients and WPA3 clients using SAE share the passphrase
+		return map[string]interface{}{
+			"key-mgmt": "wpa-psk",
+			"psk":      passphrase,
+			"pmf":      models.PMFOptional,
+		}
+	case models.PortalSecuritySAE:
+		return map[string]interface{}{
+			"key-mgmt": "sae",
+			"psk":      passphrase,
+			"pmf":      models.PMFRequired,
+		}
+	case models.PortalSecurityOWE:
+		return map[string]interface{}{
+			"key-mgmt": "owe",
+			"pmf":      models.PMFRequired,
+		}
+	}
+	return nil
+}
+
+// validateCredentials checks the request can be turned into a profile NetworkManager accepts for the security
+func validateCredentials(security models.SECURITY, req models.ConnectRequest) (err error) {
+	if (security&models.ENTERPRISE192) != models.ENTERPRISE192 && (security&models.ENTERPRISE) != models.ENTERPRISE {
+		return
+	}
+	err = req.ValidateEAP()
+	if err != nil {
+		return
+	}
+	if method, _ := req.EAP(); (security&models.ENTERPRISE192) == models.ENTERPRISE192 && method != models.EAPMethodTLS {
+		err = fmt.Errorf("network %s requires %s", req.SSID, models.EAPMethodTLS)
+	}
+	return
+}
+
+// get8021xSetting maps the EAP settings of the request onto the 802-1x setting
+func get8021xSetting(req models.ConnectRequest) map[string]interface{} {
+	method, phase2 := req.EAP()
+	setting := make(map[string]interface{})
+	setting["eap"] = []string{method}
+	setting["identity"] = req.Identity
+	if req.AnonymousIdentity != "" {
+		setting["anonymous-identity"] = req.AnonymousIdentity
+	}
+	if phase2 != "" {
+		setting["phase2-auth"] = phase2
+	}
+	if method != models.EAPMethodTLS {
+		setting["password"] = req.Passphrase
+	}
+	if req.CACert != "" {
+		setting["ca-cert"] = certificateValue(req.CACert)
+	}
+	if req.DomainSuffixMatch != "" {
+		setting["domain-suffix-match"] = req.DomainSuffixMatch
+	}
+	if req.ClientCert != "" {
+		setting["client-cert"] = certificateValue(req.ClientCert)
+	}
+	if req.PrivateKey != "" {
+		setting["private-key"] = certificateValue(req.PrivateKey)
+	}
+	if req.PrivateKeyPassword != "" {
+		setting["private-key-password"] = req.PrivateKeyPassword
+	}
+	return setting
+}
+
+// certificateValue returns a certificate or key in NetworkManager format, either
+// a "file://" path terminated by a NUL byte or the PEM/DER data itself
+func certificateValue(v string) []byte {
+	if strings.HasPrefix(v, "file://") {
+		return append([]byte(v), 0)
+	}
+	return []byte(v)
+}
+
+func getAccessPointSecurity(ap interfaces.AccessPoint) (security models.SECURITY, err error) {
 	var flag, wpaFlag, rsnFlag uint32
 
 	flag, err = ap.GetPropertyFlags()
@@ -423,59 +880,137 @@ func getAccessPointSecurity(ap gonetworkmanager.AccessPoint) (security models.SE
 		security += models.WPA
 	}
 
-	if rsnFlag != models.ApSecNone.U32() {
+	// SAE, OWE and Suite-B only networks advertise RSN without WPA2 key management
+	wpa3Mgmt := (models.ApSecKeyMgmtSAE | models.ApSecKeyMgmtOWE | models.ApSecKeyMgmtOWETM | models.ApSecKeyMgmtEAPSuiteB192).U32()
+	wpa2Mgmt := (models.ApSecKeyMGMTPSK | models.ApSecKeyMgmt8021X).U32()
+	if rsnFlag != models.ApSecNone.U32() && ((rsnFlag&wpa2Mgmt) != 0 || (rsnFlag&wpa3Mgmt) == 0) {
 		security += models.WPA2
 	}
 
 	if (wpaFlag&models.ApSecKeyMgmt8021X.U32()) == models.ApSecKeyMgmt8021X.U32() || (rsnFlag&models.ApSecKeyMgmt8021X.U32()) == models.ApSecKeyMgmt8021X.U32() {
 		security += models.ENTERPRISE
 	}
+
+	if (rsnFlag & models.ApSecKeyMgmtSAE.U32()) == models.ApSecKeyMgmtSAE.U32() {
+		security += models.SAE
+	}
+
+	if (rsnFlag&models.ApSecKeyMgmtOWE.U32()) == models.ApSecKeyMgmtOWE.U32() || (rsnFlag&models.ApSecKeyMgmtOWETM.U32()) == models.ApSecKeyMgmtOWETM.U32() {
+		security += models.OWE
+	}
+
+	if (rsnFlag & models.ApSecKeyMgmtEAPSuiteB192.U32()) == models.ApSecKeyMgmtEAPSuiteB192.U32() {
+		security += models.ENTERPRISE192
+	}
 	return
 }
 
+// waitForConnectivity waits for NetworkManager to report connectivity, following its PropertiesChanged signals
 func (c *Config) waitForConnectivity(timeout int) (bool, error) {
-	var totalTime = 0
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	start := time.Now()
+	changes, err := c.NetworkManager.SubscribeConnectivity(ctx)
+	if err != nil {
+		return false, err
+	}
+	nmConn, err := c.NetworkManager.GetPropertyConnectivity()
+	if err != nil {
+		return false, err
+	}
 	for {
-		nmConn, err := c.NetworkManager.GetPropertyConnectivity()
-		if err != nil {
-			return false, err
-		}
 		if nmConn == gonetworkmanager.NmConnectivityFull || nmConn == gonetworkmanager.NmConnectivityLimited {
-			c.Log.Debug(fmt.Sprintf("Connectivity established: %s / %ds elapsed", nmConn.String(), totalTime))
+			c.Log.Debug(fmt.Sprintf("Connectivity established: %s / %s elapsed", nmConn.String(), time.Since(start).Round(time.Millisecond)))
 			return true, nil
-		} else if totalTime >= timeout {
-			c.Log.Debug(fmt.Sprintf("Timeout reached in waiting for connectivity: %s / %ds elapsed", nmConn.String(), totalTime))
+		}
+		select {
+		case connectivity, ok := <-changes:
+			if !ok {
+				// closed once ctx is done
+				changes = nil
+				continue
+			}
+			nmConn = connectivity
+			c.Log.Debug(fmt.Sprintf("Still waiting for connectivity: %s / %s elapsed", nmConn.String(), time.Since(start).Round(time.Millisecond)))
+		case <-ctx.Done():
+			c.Log.Debug(fmt.Sprintf("Timeout reached in waiting for connectivity: %s / %s elapsed", nmConn.String(), time.Since(start).Round(time.Millisecond)))
 			return false, nil
 		}
-		totalTime = totalTime + 1
-		time.Sleep(1 * time.Second)
-		c.Log.Debug(fmt.Sprintf("Still waiting for connectivity: %s / %ds elapsed", nmConn.String(), totalTime))
 	}
 }
 
-func (c *Config) waitForConnectionState(timeout int, ac gonetworkmanager.ActiveConnection, cs gonetworkmanager.NmActiveConnectionState) (bool, error) {
-	var totalTime = 0
+// waitForConnectionState waits for the active connection to reach the state cs, following the StateChanged
+// signals of the connection and of the device d it runs on. It gives up as soon as the connection is deactivated or the device
+// fails, returning a *models.ConnectError built from the reasons NetworkManager gave.
+func (c *Config) waitForConnectionState(timeout int, d interfaces.WifiDevice, ac interfaces.ActiveConnection, cs gonetworkmanager.NmActiveConnectionState) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+	start := time.Now()
+	changes, err := ac.SubscribeState(ctx)
+	if err != nil {
+		return
+	}
+	deviceChanges, err := d.SubscribeState(ctx)
+	if err != nil {
+		return
+	}
+	state, err := ac.GetPropertyState()
+	if err != nil {
+		return
+	}
+	var failure activationFailure
 	for {
-		state, err := ac.GetPropertyState()
-		if err != nil {
-			return false, err
-		}
 		if state == cs {
-			c.Log.Debug(fmt.Sprintf("connection state matched: %s / %ds elapsed", state.String(), totalTime))
-			return true, nil
-		} else if totalTime >= timeout {
-			c.Log.Debug(fmt.Sprintf("Timeout reached in waiting for connection state: %s / %ds elapsed", cs.String(), totalTime))
-			return false, nil
+			c.Log.Debug(fmt.Sprintf("connection state matched: %s / %s elapsed", state.String(), time.Since(start).Round(time.Millisecond)))
+			return nil
+		} else if state == gonetworkmanager.NmActiveConnectionStateDeactivated {
+			// the device fails right before the connection goes down, its reason may not be received yet
+			failure.collectDeviceReason(deviceChanges)
+			err = failure.err()
+			c.Log.Debug(fmt.Sprintf("Connection deactivated while waiting for connection state: %s, %s / %s elapsed", cs.String(), err.Error(), time.Since(start).Round(time.Millisecond)))
+			return
+		}
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+			state = change.State
+			failure.acReason = change.Reason
+			c.Log.Debug(fmt.Sprintf("Still waiting for connection state: %s, required %s / %s elapsed", state.String(), cs.String(), time.Since(start).Round(time.Millisecond)))
+		case change, ok := <-deviceChanges:
+			if !ok {
+				deviceChanges = nil
+				continue
+			}
+			if change.State == gonetworkmanager.NmDeviceStateFailed {
+				failure.deviceReason = change.Reason
+				failure.hasDeviceReason = true
+				err = failure.err()
+				c.Log.Debug(fmt.Sprintf("Device failed while waiting for connection state: %s, %s / %s elapsed", cs.String(), err.Error(), time.Since(start).Round(time.Millisecond)))
+				return
+			}
+		case <-ctx.Done():
+			c.Log.Debug(fmt.Sprintf("Timeout reached in waiting for connection state: %s / %s elapsed", cs.String(), time.Since(start).Round(time.Millisecond)))
+			return models.NewConnectError(models.ConnectErrorTimeout, "connection state %s not reached after %ds", cs.String(), timeout)
 		}
-		totalTime = totalTime + 1
-		time.Sleep(1 * time.Second)
-		c.Log.Debug(fmt.Sprintf("Still waiting for connection state: %s, required %s / %ds elapsed", state.String(), cs.String(), totalTime))
 	}
 }
 
+// waitForDeactivation waits until the subscribed connection reports the deactivated state
+func (c *Config) waitForDeactivation(changes <-chan interfaces.ActiveConnectionStateChange) bool {
+	for change := range changes {
+		if change.State == gonetworkmanager.NmActiveConnectionStateDeactivated {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Config) deleteConnectionIfSameNetworkExists(ssid string) (err error) {
 	c.Log.Info("deleting existing connection of same network")
-	conns, err := c.WifiDevice.GetPropertyAvailableConnections()
+	conns, err := c.StationDevice.GetPropertyAvailableConnections()
 	if err != nil {
 		err = fmt.Errorf("found error on GetPropertyAvailableConnections - %s", err.Error())
 		return
@@ -503,57 +1038,172 @@ func (c *Config) deleteConnectionIfSameNetworkExists(ssid string) (err error) {
 	return
 }
 
-func getWifiDevice(nm gonetworkmanager.NetworkManager) (d gonetworkmanager.DeviceWireless, err error) {
-	devices, err := nm.GetAllDevices()
+// wifiDeviceCandidate describes a wireless device considered for the portal
+type wifiDeviceCandidate struct {
+	device    interfaces.WifiDevice
+	iface     string
+	hwAddress string
+	managed   bool
+	apCapable bool
+}
+
+func (w wifiDeviceCandidate) String() string {
+	var desc []string
+	if w.hwAddress != "" {
+		desc = append(desc, w.hwAddress)
+	}
+	if w.apCapable {
+		desc = append(desc, "ap")
+	} else {
+		desc = append(desc, "no ap")
+	}
+	if !w.managed {
+		desc = append(desc, "unmanaged")
+	}
+	return fmt.Sprintf("%s (%s)", w.iface, strings.Join(desc, ", "))
+}
+
+// matches tells whether the candidate is the device requested by interface name or MAC address
+func (w wifiDeviceCandidate) matches(wanted string) bool {
+	return w.iface == wanted || strings.EqualFold(w.hwAddress, wanted)
+}
+
+// getWifiDevices selects the wireless devices of the portal and of the station connection.
+// A requested portal device is looked up by interface name or MAC address and must be able to host an
+// access point, otherwise the first managed device with AP capability is preferred over the other managed
+// devices. Without a requested station device, the portal device is used for both.
+func getWifiDevices(nm interfaces.NetworkManager, wanted string, wantedStation string) (d interfaces.WifiDevice, station interfaces.WifiDevice, err error) {
+	devices, err := nm.GetWifiDevices()
 	if err != nil {
-		err = fmt.Errorf("found error on GetAllDevices [%s]", err.Error())
 		return
 	}
 
+	var candidates []wifiDeviceCandidate
 	for _, device := range devices {
-		var dType gonetworkmanager.NmDeviceType
-		dType, err = device.GetPropertyDeviceType()
+		var candidate wifiDeviceCandidate
+		candidate, err = newWifiDeviceCandidate(device)
 		if err != nil {
-			err = fmt.Errorf("found error on GetPropertyDeviceType [%s]", err.Error())
 			return
 		}
-		path := device.GetPath()
-		switch dType {
-		case gonetworkmanager.NmDeviceTypeWifi:
-			var state gonetworkmanager.NmDeviceState
-			state, err = device.GetPropertyState()
-			if err != nil {
-				err = fmt.Errorf("found error on GetPropertyState [%s]", err.Error())
+		candidates = append(candidates, candidate)
+	}
+	list := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		list[i] = candidate.String()
+	}
+
+	if wantedStation != "" {
+		for _, candidate := range candidates {
+			if !candidate.matches(wantedStation) {
+				continue
+			}
+			if !candidate.managed {
+				err = fmt.Errorf("station device %s is not managed by NetworkManager, candidates: %s", wantedStation, strings.Join(list, "; "))
+				return
+			}
+			station = candidate.device
+			break
+		}
+		if station == nil {
+			err = fmt.Errorf("could not find station device %s, candidates: %s", wantedStation, strings.Join(list, "; "))
+			return
+		}
+	}
+
+	if wanted != "" {
+		for _, candidate := range candidates {
+			if !candidate.matches(wanted) {
+				continue
+			}
+			if !candidate.managed {
+				err = fmt.Errorf("wifi device %s is not managed by NetworkManager, candidates: %s", wanted, strings.Join(list, "; "))
 				return
 			}
-			if state != gonetworkmanager.NmDeviceStateUnmanaged {
-				d, err = gonetworkmanager.NewDeviceWireless(path)
-				if err != nil {
-					err = fmt.Errorf("found error on getWirelessDevice - NewDeviceWireless [%s]", err.Error())
-					return
-				}
+			if !candidate.apCapable {
+				err = fmt.Errorf("wifi device %s can't host an access point, candidates: %s", wanted, strings.Join(list, "; "))
+				return
+			}
+			if candidate.device == station {
+				err = fmt.Errorf("wifi device %s is also the station device, dual-radio mode needs two devices", wanted)
 				return
 			}
+			d = candidate.device
+			break
+		}
+		if d == nil {
+			err = fmt.Errorf("could not find wifi device %s, candidates: %s", wanted, strings.Join(list, "; "))
+			return
+		}
+	} else {
+		for _, candidate := range candidates {
+			if !candidate.managed || candidate.device == station {
+				continue
+			}
+			if candidate.apCapable {
+				d = candidate.device
+				break
+			}
+			if d == nil {
+				d = candidate.device
+			}
+		}
+		if d == nil {
+			err = fmt.Errorf("could not find wifi device, candidates: %s", strings.Join(list, "; "))
+			return
 		}
 	}
-	err = errors.New("could not find wifi device")
+
+	if station == nil {
+		station = d
+	}
 	return
 }
 
-func (c *Config) getAccessPointFromSSID(ssid string) (accessPoint gonetworkmanager.AccessPoint, err error) {
-	var aPoints []AccessPoint
-	aPoints, err = c.getAccessPoint(10)
+func newWifiDeviceCandidate(device interfaces.WifiDevice) (candidate wifiDeviceCandidate, err error) {
+	candidate.device = device
+	candidate.iface, err = device.GetPropertyInterface()
+	if err != nil {
+		err = fmt.Errorf("found error on GetPropertyInterface [%s]", err.Error())
+		return
+	}
+	candidate.hwAddress, err = device.GetPropertyHwAddress()
+	if err != nil {
+		err = fmt.Errorf("found error on GetPropertyHwAddress [%s]", err.Error())
+		return
+	}
+	var state gonetworkmanager.NmDeviceState
+	state, err = device.GetPropertyState()
 	if err != nil {
+		err = fmt.Errorf("found error on GetPropertyState [%s]", err.Error())
+		return
+	}
+	candidate.managed = state != gonetworkmanager.NmDeviceStateUnmanaged
+	var caps uint32
+	caps, err = device.GetPropertyWirelessCapabilities()
+	if err != nil {
+		err = fmt.Errorf("found error on GetPropertyWirelessCapabilities [%s]", err.Error())
+		return
+	}
+	candidate.apCapable = caps&models.WifiDeviceCapAP.U32() != 0
+	return
+}
+
+func (c *Config) getAccessPointFromSSID(ssid string) (accessPoint interfaces.AccessPoint, err error) {
+	var aPoints []AccessPoint
+	aPoints, err = c.getAccessPoint(context.Background(), 10)
+	if err == errNoAccessPoint {
+		err = models.NewConnectError(models.ConnectErrorAPNotFound, "found error on GetAccessPoints: %s", err.Error())
+		return
+	} else if err != nil {
 		err = fmt.Errorf("found error on GetAccessPoints: %s", err.Error())
 		return
 	}
-	fmt.Println(aPoints)
 	for _, aPoint := range aPoints {
 		if aPoint.SSID == ssid {
-			accessPoint, err = gonetworkmanager.NewAccessPoint(aPoint.Path)
+			accessPoint = aPoint.AP
 			return
 		}
 	}
-	err = fmt.Errorf("could not found accesspoint with ssid: %s", ssid)
+	err = models.NewConnectError(models.ConnectErrorAPNotFound, "could not found accesspoint with ssid: %s", ssid)
 	return
 }
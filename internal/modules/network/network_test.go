@@ -0,0 +1,786 @@
+package network
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/sirupsen/logrus"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+// testStepInterval keeps the scripted activations of the fake short
+const testStepInterval = 10 * time.Millisecond
+
+type testCommand struct {
+	dnsmasqStarts int
+	dnsmasqKills  int
+}
+
+func (t *testCommand) StartDnsmasq(dInt string, subnet models.PortalSubnet) { t.dnsmasqStarts++ }
+func (t *testCommand) KillDNSMasq()                                         { t.dnsmasqKills++ }
+func (t *testCommand) GetProcessStatus() (status []models.ProcessStatus)    { return }
+func (t *testCommand) GetDnsmasqLeases() (leases []models.Lease, err error) { return }
+
+type testHTTPServer struct {
+	starts int
+	closes int
+}
+
+func (t *testHTTPServer) StartHTTPServer() { t.starts++ }
+func (t *testHTTPServer) CloseHTTPServer() { t.closes++ }
+
+func testConfig() *models.Config {
+	return &models.Config{
+		Gateway:    "192.168.42.1",
+		DHCPRange:  "192.168.42.2,192.168.42.254",
+		DHCPServer: models.DHCPServerDnsmasq,
+		SSID:       "WiFi Connect",
+		Band:       models.HotspotBandBG,
+		ScanTTL:    120,
+	}
+}
+
+func testLogger() *logrus.Logger {
+	l := logrus.New()
+	l.Out = io.Discard
+	return l
+}
+
+func newTestNetwork(t *testing.T, nm interfaces.NetworkManager, cfg *models.Config) *Config {
+	t.Helper()
+	l := testLogger()
+	var ps interfaces.PortalServer
+	c, err := NewNetwork(l, &testCommand{}, ps, nm, cfg)
+	if err != nil {
+		t.Fatalf("NewNetwork: %s", err.Error())
+	}
+	c.HTTPServer = &testHTTPServer{}
+	return c
+}
+
+// apActivations returns the activations of access point profiles recorded by the fake
+func apActivations(nm *fake.NetworkManager) (activations []*fake.ActiveConnection) {
+	for _, ac := range nm.ActiveConnections {
+		if mode, _ := ac.Connection.Settings["802-11-wireless"]["mode"].(string); mode == "ap" {
+			activations = append(activations, ac)
+		}
+	}
+	return
+}
+
+// savedStationProfile returns the saved client profile of the SSID, nil when there is none
+func savedStationProfile(nm *fake.NetworkManager, ssid string) *fake.Connection {
+	for _, conn := range nm.Saved {
+		wireless := conn.Settings["802-11-wireless"]
+		if mode, _ := wireless["mode"].(string); mode == "ap" {
+			continue
+		}
+		if s, _ := wireless["ssid"].([]byte); string(s) == ssid {
+			return conn
+		}
+	}
+	return nil
+}
+
+func TestCreateHotSpot(t *testing.T) {
+	defer func(timeout int, interval time.Duration) {
+		activationTimeout, scanRetryInterval = timeout, interval
+	}(activationTimeout, scanRetryInterval)
+	activationTimeout, scanRetryInterval = 1, time.Millisecond
+
+	activating := gonetworkmanager.NmActiveConnectionStateActivating
+	deactivated := gonetworkmanager.NmActiveConnectionStateDeactivated
+	tests := []struct {
+		name       string
+		passphrase string
+		scan       []*fake.AccessPoint
+		activation []gonetworkmanager.NmActiveConnectionState
+		// wantCode is empty for a hotspot created
+		wantCode models.ConnectErrorCode
+		wantSSID string
+		wantPSK  string
+	}{
+		{
+			name:     "open portal",
+			scan:     []*fake.AccessPoint{fake.WPA2AccessPoint("home", 80)},
+			wantSSID: "WiFi Connect",
+		},
+		{
+			name:       "wpa2 portal",
+			passphrase: "portal-secret",
+			scan:       []*fake.AccessPoint{fake.WPA2AccessPoint("home", 80)},
+			wantSSID:   "WiFi Connect",
+			wantPSK:    "portal-secret",
+		},
+		{
+			// hidden networks can still be joined from the portal
+			name:     "empty scan",
+			wantSSID: "WiFi Connect",
+		},
+		{
+			name:     "ssid used nearby",
+			scan:     []*fake.AccessPoint{fake.OpenAccessPoint("WiFi Connect", 40), fake.WPA2AccessPoint("home", 80)},
+			wantSSID: "WiFi Connect-2",
+		},
+		{
+			name:       "activation failed",
+			scan:       []*fake.AccessPoint{fake.WPA2AccessPoint("home", 80)},
+			activation: []gonetworkmanager.NmActiveConnectionState{activating, deactivated},
+			wantCode:   models.ConnectErrorActivationFailed,
+		},
+		{
+			name:       "activation timeout",
+			scan:       []*fake.AccessPoint{fake.WPA2AccessPoint("home", 80)},
+			activation: []gonetworkmanager.NmActiveConnectionState{activating},
+			wantCode:   models.ConnectErrorTimeout,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			nm.StepInterval = testStepInterval
+			nm.AddWifiDevice("wlan0", tt.scan)
+			if tt.activation != nil {
+				nm.Activations = [][]gonetworkmanager.NmActiveConnectionState{tt.activation}
+			}
+			cfg := testConfig()
+			cfg.Passphrase = tt.passphrase
+			c := newTestNetwork(t, nm, cfg)
+
+			err := c.CreateHotSpot()
+			if tt.wantCode != "" {
+				var connectErr *models.ConnectError
+				if !errors.As(err, &connectErr) || connectErr.Code != tt.wantCode {
+					t.Fatalf("CreateHotSpot returned %v, want a ConnectError %s", err, tt.wantCode)
+				}
+				if c.isHotSpotCreated || c.HotSpotConnection != nil {
+					t.Errorf("hotspot recorded as created after a failed activation")
+				}
+				if len(nm.Saved) != 0 {
+					t.Errorf("%d profiles kept after a failed activation, want 0", len(nm.Saved))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateHotSpot: %s", err.Error())
+			}
+			if !c.isHotSpotCreated || c.HotSpotSSID != tt.wantSSID {
+				t.Errorf("hotspot created %t with SSID %q, want true with %q", c.isHotSpotCreated, c.HotSpotSSID, tt.wantSSID)
+			}
+			activations := apActivations(nm)
+			if len(activations) != 1 {
+				t.Fatalf("%d access point activations, want 1", len(activations))
+			}
+			settings := activations[0].Connection.Settings
+			if ssid, _ := settings["802-11-wireless"]["ssid"].([]byte); string(ssid) != tt.wantSSID {
+				t.Errorf("profile SSID %q, want %q", ssid, tt.wantSSID)
+			}
+			psk, _ := settings["802-11-wireless-security"]["psk"].(string)
+			if psk != tt.wantPSK {
+				t.Errorf("profile psk %q, want %q", psk, tt.wantPSK)
+			}
+			if got := c.CMD.(*testCommand).dnsmasqStarts; got != 1 {
+				t.Errorf("dnsmasq started %d times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestConnect(t *testing.T) {
+	defer func(activation int, connectivity int) {
+		activationTimeout, connectivityTimeout = activation, connectivity
+	}(activationTimeout, connectivityTimeout)
+	activationTimeout, connectivityTimeout = 1, 1
+
+	activating := gonetworkmanager.NmActiveConnectionStateActivating
+	deactivated := gonetworkmanager.NmActiveConnectionStateDeactivated
+	scan := []*fake.AccessPoint{
+		fake.WPA2AccessPoint("home", 80),
+		fake.OpenAccessPoint("cafe", 60),
+		fake.EnterpriseAccessPoint("office", 40),
+	}
+	tests := []struct {
+		name         string
+		dualRadio    bool
+		req          models.ConnectRequest
+		activation   []gonetworkmanager.NmActiveConnectionState
+		reasons      []gonetworkmanager.NmDeviceStateReason
+		connectivity []gonetworkmanager.NmConnectivity
+		// wantCode is empty for a successful connection
+		wantCode       models.ConnectErrorCode
+		wantPortal     bool
+		wantPortalRuns int
+	}{
+		{
+			name:           "wpa2 network",
+			req:            models.ConnectRequest{SSID: "home", Passphrase: "home-secret"},
+			wantPortalRuns: 1,
+		},
+		{
+			name:           "open network",
+			req:            models.ConnectRequest{SSID: "cafe"},
+			wantPortalRuns: 1,
+		},
+		{
+			name:           "wrong passphrase",
+			req:            models.ConnectRequest{SSID: "home", Passphrase: "wrong-secret"},
+			activation:     []gonetworkmanager.NmActiveConnectionState{activating, deactivated},
+			reasons:        []gonetworkmanager.NmDeviceStateReason{gonetworkmanager.NmDeviceStateReasonNoSecrets},
+			wantCode:       models.ConnectErrorAuthFailed,
+			wantPortal:     true,
+			wantPortalRuns: 2,
+		},
+		{
+			name:           "activation timeout",
+			req:            models.ConnectRequest{SSID: "home", Passphrase: "home-secret"},
+			activation:     []gonetworkmanager.NmActiveConnectionState{activating},
+			wantCode:       models.ConnectErrorTimeout,
+			wantPortal:     true,
+			wantPortalRuns: 2,
+		},
+		{
+			name:           "network out of range",
+			req:            models.ConnectRequest{SSID: "elsewhere", Passphrase: "home-secret"},
+			wantCode:       models.ConnectErrorAPNotFound,
+			wantPortal:     true,
+			wantPortalRuns: 2,
+		},
+		{
+			name:           "enterprise network without identity",
+			req:            models.ConnectRequest{SSID: "office", Passphrase: "office-secret"},
+			wantCode:       models.ConnectErrorInvalidRequest,
+			wantPortal:     true,
+			wantPortalRuns: 1,
+		},
+		{
+			name:           "hidden network",
+			req:            models.ConnectRequest{SSID: "attic", Hidden: true, Security: "wpa2", Passphrase: "attic-secret"},
+			wantPortalRuns: 1,
+		},
+		{
+			name:           "dual radio network joined",
+			dualRadio:      true,
+			req:            models.ConnectRequest{SSID: "home", Passphrase: "home-secret"},
+			wantPortal:     true,
+			wantPortalRuns: 1,
+		},
+		{
+			name:           "dual radio wrong passphrase",
+			dualRadio:      true,
+			req:            models.ConnectRequest{SSID: "home", Passphrase: "wrong-secret"},
+			activation:     []gonetworkmanager.NmActiveConnectionState{activating, deactivated},
+			reasons:        []gonetworkmanager.NmDeviceStateReason{gonetworkmanager.NmDeviceStateReasonSupplicantDisconnect},
+			wantCode:       models.ConnectErrorAuthFailed,
+			wantPortal:     true,
+			wantPortalRuns: 1,
+		},
+		{
+			name:           "dual radio without connectivity",
+			dualRadio:      true,
+			req:            models.ConnectRequest{SSID: "home", Passphrase: "home-secret"},
+			connectivity:   []gonetworkmanager.NmConnectivity{gonetworkmanager.NmConnectivityNone},
+			wantCode:       models.ConnectErrorNoConnectivity,
+			wantPortal:     true,
+			wantPortalRuns: 1,
+		},
+		{
+			name:           "single radio without connectivity",
+			req:            models.ConnectRequest{SSID: "home", Passphrase: "home-secret"},
+			connectivity:   []gonetworkmanager.NmConnectivity{gonetworkmanager.NmConnectivityNone},
+			wantPortalRuns: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			nm.StepInterval = testStepInterval
+			nm.AddWifiDevice("wlan0", scan)
+			cfg := testConfig()
+			if tt.dualRadio {
+				nm.AddWifiDevice("wlan1", scan)
+				cfg.StationInterface = "wlan1"
+			}
+			c := newTestNetwork(t, nm, cfg)
+			err := c.CreateHotSpot()
+			if err != nil {
+				t.Fatalf("CreateHotSpot: %s", err.Error())
+			}
+			if tt.activation != nil {
+				nm.Activations = [][]gonetworkmanager.NmActiveConnectionState{tt.activation}
+			}
+			nm.FailureReasons = tt.reasons
+			nm.Connectivity = tt.connectivity
+
+			err = c.Connect(tt.req)
+			var connectErr *models.ConnectError
+			switch {
+			case tt.wantCode == "" && err != nil:
+				t.Fatalf("Connect: %s", err.Error())
+			case tt.wantCode != "" && !errors.As(err, &connectErr):
+				t.Fatalf("Connect returned %v, want a ConnectError %s", err, tt.wantCode)
+			case tt.wantCode != "" && connectErr.Code != tt.wantCode:
+				t.Fatalf("Connect returned %s, want %s", connectErr.Code, tt.wantCode)
+			}
+			if c.isHotSpotCreated != tt.wantPortal {
+				t.Errorf("portal running %t, want %t", c.isHotSpotCreated, tt.wantPortal)
+			}
+			if runs := len(apActivations(nm)); runs != tt.wantPortalRuns {
+				t.Errorf("portal activated %d times, want %d", runs, tt.wantPortalRuns)
+			}
+			profile := savedStationProfile(nm, tt.req.SSID)
+			if tt.wantCode != "" {
+				if profile != nil {
+					t.Errorf("profile of %s kept after a failed connection", tt.req.SSID)
+				}
+				return
+			}
+			if profile == nil {
+				t.Fatalf("no profile saved for %s", tt.req.SSID)
+			}
+			if psk, _ := profile.Settings["802-11-wireless-security"]["psk"].(string); psk != tt.req.Passphrase {
+				t.Errorf("profile psk %q, want %q", psk, tt.req.Passphrase)
+			}
+			if hidden, _ := profile.Settings["802-11-wireless"]["hidden"].(bool); hidden != tt.req.Hidden {
+				t.Errorf("profile hidden %t, want %t", hidden, tt.req.Hidden)
+			}
+		})
+	}
+}
+
+func TestConnectSecurity(t *testing.T) {
+	defer func(activation int, connectivity int) {
+		activationTimeout, connectivityTimeout = activation, connectivity
+	}(activationTimeout, connectivityTimeout)
+	activationTimeout, connectivityTimeout = 1, 1
+
+	tlsRequest := models.ConnectRequest{
+		Identity:   "device",
+		EAPMethod:  models.EAPMethodTLS,
+		ClientCert: "file:///etc/wifi/client.pem",
+		PrivateKey: "file:///etc/wifi/client.key",
+	}
+	tests := []struct {
+		name         string
+		ap           *fake.AccessPoint
+		req          models.ConnectRequest
+		wantSecurity models.SECURITY
+		// wantKeyMgmt is empty when the profile has no security setting, wantPMF is 0 when pmf is not set
+		wantKeyMgmt string
+		wantPMF     int32
+		wantPSK     string
+		want8021x   bool
+		// wantCode is set when the request is refused
+		wantCode models.ConnectErrorCode
+	}{
+		{
+			name:         "open",
+			ap:           fake.OpenAccessPoint("net", 50),
+			wantSecurity: models.NONE,
+		},
+		{
+			name:         "wep",
+			ap:           &fake.AccessPoint{SSID: "net", Flags: models.ApFlagsPrivacy.U32(), Strength: 50},
+			req:          models.ConnectRequest{Passphrase: "0123456789"},
+			wantSecurity: models.WEP,
+		},
+		{
+			name: "wpa",
+			ap: &fake.AccessPoint{SSID: "net", Flags: models.ApFlagsPrivacy.U32(), Strength: 50,
+				WPAFlags: (models.ApSecPairTKIP | models.ApSecGroupTKIP | models.ApSecKeyMGMTPSK).U32()},
+			req:          models.ConnectRequest{Passphrase: "net-secret"},
+			wantSecurity: models.WPA,
+			wantKeyMgmt:  "wpa-psk",
+			wantPSK:      "net-secret",
+		},
+		{
+			name:         "wpa2",
+			ap:           fake.WPA2AccessPoint("net", 50),
+			req:          models.ConnectRequest{Passphrase: "net-secret"},
+			wantSecurity: models.WPA2,
+			wantKeyMgmt:  "wpa-psk",
+			wantPSK:      "net-secret",
+		},
+		{
+			name:         "wpa2/wpa3 transition",
+			ap:           fake.TransitionAccessPoint("net", 50),
+			req:          models.ConnectRequest{Passphrase: "net-secret"},
+			wantSecurity: models.WPA2 | models.SAE,
+			wantKeyMgmt:  "wpa-psk",
+			wantPMF:      models.PMFOptional,
+			wantPSK:      "net-secret",
+		},
+		{
+			name:         "sae only",
+			ap:           fake.WPA3AccessPoint("net", 50),
+			req:          models.ConnectRequest{Passphrase: "net-secret"},
+			wantSecurity: models.SAE,
+			wantKeyMgmt:  "sae",
+			wantPMF:      models.PMFRequired,
+			wantPSK:      "net-secret",
+		},
+		{
+			name:         "owe",
+			ap:           fake.OWEAccessPoint("net", 50),
+			wantSecurity: models.OWE,
+			wantKeyMgmt:  "owe",
+			wantPMF:      models.PMFRequired,
+		},
+		{
+			name:         "owe transition mode",
+			ap:           fake.OWETransitionAccessPoint("net", 50),
+			wantSecurity: models.OWE,
+			wantKeyMgmt:  "owe",
+			wantPMF:      models.PMFRequired,
+		},
+		{
+			name:         "wpa2 enterprise",
+			ap:           fake.EnterpriseAccessPoint("net", 50),
+			req:          models.ConnectRequest{Identity: "user", Passphrase: "user-secret"},
+			wantSecurity: models.WPA2 | models.ENTERPRISE,
+			wantKeyMgmt:  "wpa-eap",
+			want8021x:    true,
+		},
+		{
+			name:         "suite-b-192",
+			ap:           fake.SuiteB192AccessPoint("net", 50),
+			req:          tlsRequest,
+			wantSecurity: models.ENTERPRISE192,
+			wantKeyMgmt:  "wpa-eap-suite-b-192",
+			wantPMF:      models.PMFRequired,
+			want8021x:    true,
+		},
+		{
+			name:         "suite-b-192 without tls",
+			ap:           fake.SuiteB192AccessPoint("net", 50),
+			req:          models.ConnectRequest{Identity: "user", Passphrase: "user-secret"},
+			wantSecurity: models.ENTERPRISE192,
+			wantCode:     models.ConnectErrorInvalidRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			security, err := getAccessPointSecurity(tt.ap)
+			if err != nil {
+				t.Fatalf("getAccessPointSecurity: %s", err.Error())
+			}
+			if security != tt.wantSecurity {
+				t.Errorf("security %s (%b), want %s (%b)", security, security, tt.wantSecurity, tt.wantSecurity)
+			}
+
+			nm := fake.NewNetworkManager()
+			nm.StepInterval = testStepInterval
+			nm.AddWifiDevice("wlan0", []*fake.AccessPoint{tt.ap})
+			c := newTestNetwork(t, nm, testConfig())
+			aps, err := c.readAccessPoints()
+			if err != nil {
+				t.Fatalf("readAccessPoints: %s", err.Error())
+			}
+			c.updateAccessPoints(aps)
+			req := tt.req
+			req.SSID = "net"
+			err = c.Connect(req)
+			if tt.wantCode != "" {
+				var connectErr *models.ConnectError
+				if !errors.As(err, &connectErr) || connectErr.Code != tt.wantCode {
+					t.Fatalf("Connect returned %v, want a ConnectError %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Connect: %s", err.Error())
+			}
+			profile := savedStationProfile(nm, "net")
+			if profile == nil {
+				t.Fatalf("no profile saved")
+			}
+			wsec, hasSecurity := profile.Settings["802-11-wireless-security"]
+			if tt.wantSecurity == models.NONE {
+				if hasSecurity {
+					t.Errorf("security setting %v on an open network", wsec)
+				}
+				return
+			}
+			if !hasSecurity {
+				t.Fatalf("no security setting")
+			}
+			if tt.wantSecurity == models.WEP {
+				if wsec["wep-key0"] != tt.req.Passphrase || wsec["wep-key-type"] != uint32(2) {
+					t.Errorf("wep setting %v", wsec)
+				}
+				return
+			}
+			if keyMgmt, _ := wsec["key-mgmt"].(string); keyMgmt != tt.wantKeyMgmt {
+				t.Errorf("key-mgmt %q, want %q", keyMgmt, tt.wantKeyMgmt)
+			}
+			pmf, hasPMF := wsec["pmf"].(int32)
+			if tt.wantPMF == 0 && hasPMF || tt.wantPMF != 0 && pmf != tt.wantPMF {
+				t.Errorf("pmf %v, want %d", wsec["pmf"], tt.wantPMF)
+			}
+			if psk, _ := wsec["psk"].(string); psk != tt.wantPSK {
+				t.Errorf("psk %q, want %q", psk, tt.wantPSK)
+			}
+			if _, has8021x := profile.Settings["802-1x"]; has8021x != tt.want8021x {
+				t.Errorf("802-1x setting %t, want %t", has8021x, tt.want8021x)
+			}
+		})
+	}
+}
+
+func TestGet8021xSetting(t *testing.T) {
+	setting := get8021xSetting(models.ConnectRequest{
+		Identity:           "device",
+		AnonymousIdentity:  "anonymous",
+		EAPMethod:          models.EAPMethodTLS,
+		DomainSuffixMatch:  "example.com",
+		CACert:             "-----BEGIN CERTIFICATE-----",
+		ClientCert:         "file:///etc/wifi/client.pem",
+		PrivateKey:         "file:///etc/wifi/client.key",
+		PrivateKeyPassword: "key-secret",
+	})
+	if eap, _ := setting["eap"].([]string); len(eap) != 1 || eap[0] != models.EAPMethodTLS {
+		t.Errorf("eap %v, want [tls]", setting["eap"])
+	}
+	want := map[string]interface{}{
+		"identity":             "device",
+		"anonymous-identity":   "anonymous",
+		"domain-suffix-match":  "example.com",
+		"private-key-password": "key-secret",
+	}
+	for key, value := range want {
+		if setting[key] != value {
+			t.Errorf("%s %v, want %v", key, setting[key], value)
+		}
+	}
+	// paths are passed as NUL-terminated file:// URIs, data as is
+	wantBytes := map[string][]byte{
+		"ca-cert":     []byte("-----BEGIN CERTIFICATE-----"),
+		"client-cert": []byte("file:///etc/wifi/client.pem\x00"),
+		"private-key": []byte("file:///etc/wifi/client.key\x00"),
+	}
+	for key, value := range wantBytes {
+		if got, _ := setting[key].([]byte); !bytes.Equal(got, value) {
+			t.Errorf("%s %q, want %q", key, got, value)
+		}
+	}
+	for _, key := range []string{"password", "phase2-auth"} {
+		if _, ok := setting[key]; ok {
+			t.Errorf("%s set with tls", key)
+		}
+	}
+
+	setting = get8021xSetting(models.ConnectRequest{Identity: "user", Passphrase: "secret", EAPMethod: models.EAPMethodTTLS, Phase2Auth: "pap"})
+	if setting["password"] != "secret" || setting["phase2-auth"] != "pap" {
+		t.Errorf("ttls setting %v, want the password and phase2-auth pap", setting)
+	}
+}
+
+func TestReadAccessPoints(t *testing.T) {
+	// home is seen through three BSSIDs, the strongest on 5 GHz and the fastest on 2.4 GHz
+	home24 := fake.WPA2AccessPoint("home", 40)
+	home24.Frequency, home24.MaxBitrate = 2412, 1200000
+	home5 := fake.WPA2AccessPoint("home", 75)
+	home5.Frequency, home5.MaxBitrate = 5180, 866000
+	homeOther := fake.WPA2AccessPoint("home", 60)
+	homeOther.Frequency, homeOther.MaxBitrate = 2437, 144000
+	cafe := fake.OpenAccessPoint("cafe", 50)
+	cafe.Frequency, cafe.MaxBitrate = 2462, 54000
+	// hidden networks have no SSID and are left out
+	hidden := fake.WPA2AccessPoint("", 90)
+	hidden.Frequency = 2412
+
+	nm := fake.NewNetworkManager()
+	nm.AddWifiDevice("wlan0", []*fake.AccessPoint{home24, cafe, home5, hidden, homeOther})
+	nm.AddConnection(map[string]map[string]interface{}{
+		"connection":      {"id": "home", "type": "802-11-wireless"},
+		"802-11-wireless": {"ssid": []byte("home"), "mode": "infrastructure"},
+	})
+	c := newTestNetwork(t, nm, testConfig())
+	aps, err := c.readAccessPoints()
+	if err != nil {
+		t.Fatalf("readAccessPoints: %s", err.Error())
+	}
+	if len(aps) != 2 || aps[0].SSID != "home" || aps[1].SSID != "cafe" {
+		t.Fatalf("access points %+v, want home then cafe", aps)
+	}
+
+	home := aps[0]
+	if home.BSSIDCount != 3 {
+		t.Errorf("home BSSID count %d, want 3", home.BSSIDCount)
+	}
+	if len(home.Bands) != 2 || home.Bands[0] != models.Band24GHz || home.Bands[1] != models.Band5GHz {
+		t.Errorf("home bands %v, want [%s %s]", home.Bands, models.Band24GHz, models.Band5GHz)
+	}
+	if home.MaxBitrate != 1200000 {
+		t.Errorf("home max bitrate %d, want the fastest BSSID 1200000", home.MaxBitrate)
+	}
+	if home.Strength != 75 || home.Frequency != 5180 || home.AP != home5 {
+		t.Errorf("home strength %d frequency %d, want the strongest BSSID 75 on 5180", home.Strength, home.Frequency)
+	}
+	if channel := models.FrequencyToChannel(home.Frequency); channel != 36 {
+		t.Errorf("home channel %d, want 36", channel)
+	}
+	if !home.Saved || home.Security != models.WPA2 {
+		t.Errorf("home saved %t security %s, want saved WPA2", home.Saved, home.Security)
+	}
+
+	if cafe := aps[1]; cafe.BSSIDCount != 1 || len(cafe.Bands) != 1 || cafe.Bands[0] != models.Band24GHz || cafe.Saved {
+		t.Errorf("cafe %+v, want a single unsaved 2.4 GHz BSSID", cafe)
+	}
+}
+
+func TestGetWifiDevices(t *testing.T) {
+	const candidates = "candidates: wlan0 (02:00:00:00:00:01, no ap); wlan1 (02:00:00:00:00:0a, ap)"
+	tests := []struct {
+		name          string
+		wanted        string
+		wantedStation string
+		// unmanaged lists the interfaces NetworkManager does not manage
+		unmanaged   []string
+		wantDevice  string
+		wantStation string
+		// wantErr is the error returned, empty on success
+		wantErr string
+	}{
+		{name: "ap capable device preferred", wantDevice: "wlan1", wantStation: "wlan1"},
+		{name: "unmanaged ap capable device skipped", unmanaged: []string{"wlan1"}, wantDevice: "wlan0", wantStation: "wlan0"},
+		{name: "by interface name", wanted: "wlan1", wantDevice: "wlan1", wantStation: "wlan1"},
+		{name: "by mac address", wanted: "02:00:00:00:00:0A", wantDevice: "wlan1", wantStation: "wlan1"},
+		{name: "dual radio", wantedStation: "02:00:00:00:00:01", wantDevice: "wlan1", wantStation: "wlan0"},
+		{name: "requested device without ap", wanted: "wlan0", wantErr: "wifi device wlan0 can't host an access point, " + candidates},
+		{name: "unknown device", wanted: "wlan9", wantErr: "could not find wifi device wlan9, " + candidates},
+		{name: "unknown station device", wantedStation: "wlan9", wantErr: "could not find station device wlan9, " + candidates},
+		{
+			name:      "unmanaged requested device",
+			wanted:    "wlan1",
+			unmanaged: []string{"wlan1"},
+			wantErr:   "wifi device wlan1 is not managed by NetworkManager, candidates: wlan0 (02:00:00:00:00:01, no ap); wlan1 (02:00:00:00:00:0a, ap, unmanaged)",
+		},
+		{
+			name:          "station device also requested for the portal",
+			wanted:        "wlan1",
+			wantedStation: "wlan1",
+			wantErr:       "wifi device wlan1 is also the station device, dual-radio mode needs two devices",
+		},
+		{
+			name:      "no managed device",
+			unmanaged: []string{"wlan0", "wlan1"},
+			wantErr:   "could not find wifi device, candidates: wlan0 (02:00:00:00:00:01, no ap, unmanaged); wlan1 (02:00:00:00:00:0a, ap, unmanaged)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			noAP := nm.AddWifiDevice("wlan0")
+			noAP.HWAddress = "02:00:00:00:00:01"
+			noAP.Capabilities &^= models.WifiDeviceCapAP.U32()
+			ap := nm.AddWifiDevice("wlan1")
+			ap.HWAddress = "02:00:00:00:00:0a"
+			for _, device := range nm.Devices {
+				if containsString(tt.unmanaged, device.Interface) {
+					device.State = gonetworkmanager.NmDeviceStateUnmanaged
+				}
+			}
+
+			d, station, err := getWifiDevices(nm, tt.wanted, tt.wantedStation)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("getWifiDevices returned %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getWifiDevices: %s", err.Error())
+			}
+			if got := d.(*fake.WifiDevice).Interface; got != tt.wantDevice {
+				t.Errorf("portal device %s, want %s", got, tt.wantDevice)
+			}
+			if got := station.(*fake.WifiDevice).Interface; got != tt.wantStation {
+				t.Errorf("station device %s, want %s", got, tt.wantStation)
+			}
+		})
+	}
+}
+
+func TestCreateHotSpotIPv6(t *testing.T) {
+	defer func(timeout int) { activationTimeout = timeout }(activationTimeout)
+	activationTimeout = 1
+
+	tests := []struct {
+		name        string
+		prefix      string
+		wantAddress string
+		wantErr     bool
+	}{
+		{name: "unique local prefix", prefix: "fd42:42:42:42::/64", wantAddress: "fd42:42:42:42::1"},
+		{name: "global prefix", prefix: "2001:db8::/64", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			nm.StepInterval = testStepInterval
+			nm.AddWifiDevice("wlan0", []*fake.AccessPoint{fake.WPA2AccessPoint("home", 80)})
+			cfg := testConfig()
+			cfg.IPv6, cfg.IPv6Prefix = true, tt.prefix
+			c := newTestNetwork(t, nm, cfg)
+
+			err := c.CreateHotSpot()
+			if tt.wantErr {
+				if err == nil || c.isHotSpotCreated || len(apActivations(nm)) != 0 {
+					t.Errorf("CreateHotSpot returned %v with %d activations, want an error and no portal", err, len(apActivations(nm)))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateHotSpot: %s", err.Error())
+			}
+			activations := apActivations(nm)
+			if len(activations) != 1 {
+				t.Fatalf("%d access point activations, want 1", len(activations))
+			}
+			ipv6 := activations[0].Connection.Settings["ipv6"]
+			addressData, _ := ipv6["address-data"].([]map[string]interface{})
+			if ipv6["method"] != "manual" || len(addressData) != 1 || addressData[0]["address"] != tt.wantAddress || addressData[0]["prefix"] != uint32(64) {
+				t.Errorf("ipv6 setting %v, want manual %s/64", ipv6, tt.wantAddress)
+			}
+		})
+	}
+}
+
+func TestGetHotSpotSecuritySettings(t *testing.T) {
+	tests := []struct {
+		security string
+		// wantKeyMgmt is empty for an open portal without security setting
+		wantKeyMgmt string
+		wantPMF     int32
+		wantPSK     bool
+	}{
+		{security: models.PortalSecurityOpen},
+		{security: models.PortalSecurityWPA2, wantKeyMgmt: "wpa-psk", wantPMF: models.PMFDisable, wantPSK: true},
+		{security: models.PortalSecurityTransition, wantKeyMgmt: "wpa-psk", wantPMF: models.PMFOptional, wantPSK: true},
+		{security: models.PortalSecuritySAE, wantKeyMgmt: "sae", wantPMF: models.PMFRequired, wantPSK: true},
+		{security: models.PortalSecurityOWE, wantKeyMgmt: "owe", wantPMF: models.PMFRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.security, func(t *testing.T) {
+			setting := getHotSpotSecuritySettings(tt.security, "portal-secret")
+			if tt.wantKeyMgmt == "" {
+				if setting != nil {
+					t.Errorf("security setting %v, want none", setting)
+				}
+				return
+			}
+			if setting["key-mgmt"] != tt.wantKeyMgmt || setting["pmf"] != tt.wantPMF {
+				t.Errorf("key-mgmt %v pmf %v, want %s and %d", setting["key-mgmt"], setting["pmf"], tt.wantKeyMgmt, tt.wantPMF)
+			}
+			if psk, ok := setting["psk"]; ok != tt.wantPSK || ok && psk != "portal-secret" {
+				t.Errorf("psk %v, want it set %t", psk, tt.wantPSK)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package network
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+// passphraseAlphabet leaves out the characters easily mistaken for one another when read from a label
+const passphraseAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// passphraseLength gives random passphrases about 116 bits of entropy
+const passphraseLength = 20
+
+// hotSpotPassphrase returns the passphrase of the hotspot. A random passphrase is generated once,
+// or whenever rotate is set when rotation is enabled, and written to the passphrase file.
+func (c *Config) hotSpotPassphrase(rotate bool) (passphrase string, err error) {
+	cfg := c.Cfg.Fetch()
+	if !cfg.IsRandomPassphrase() {
+		passphrase = cfg.Passphrase
+		if passphrase != "" && passphrase != c.HotSpotPassphrase && cfg.PassphraseFile != "" {
+			err = writePrivateFile(cfg.PassphraseFile, []byte(passphrase+"\n"))
+		}
+		if err == nil {
+			c.HotSpotPassphrase = passphrase
+		}
+		return
+	}
+	if c.HotSpotPassphrase != "" && !(cfg.RotatePassphrase && rotate) {
+		return c.HotSpotPassphrase, nil
+	}
+	passphrase, err = randomPassphrase()
+	if err != nil {
+		err = fmt.Errorf("found error on randomPassphrase [%s]", err.Error())
+		return
+	}
+	if cfg.PassphraseFile != "" {
+		err = writePrivateFile(cfg.PassphraseFile, []byte(passphrase+"\n"))
+		if err != nil {
+			return
+		}
+		c.Log.Info(fmt.Sprintf("hotSpotPassphrase - new passphrase written to %s", cfg.PassphraseFile))
+	} else {
+		c.Log.Info("hotSpotPassphrase - new passphrase generated")
+	}
+	c.HotSpotPassphrase = passphrase
+	return
+}
+
+// randomPassphrase returns a passphrase drawn from the system random source
+func randomPassphrase() (string, error) {
+	passphrase := make([]byte, passphraseLength)
+	max := big.NewInt(int64(len(passphraseAlphabet)))
+	for i := range passphrase {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		passphrase[i] = passphraseAlphabet[n.Int64()]
+	}
+	return string(passphrase), nil
+}
+
+// writePrivateFile replaces the file with the data, readers never see a partly written file
+// and the file is only readable by its owner
+func writePrivateFile(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		err = fmt.Errorf("found error on creating %s [%s]", path, err.Error())
+		return
+	}
+	defer os.Remove(tmp.Name())
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		err = fmt.Errorf("found error on writing %s [%s]", path, err.Error())
+	}
+	return
+}
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+func TestRandomPassphraseRotation(t *testing.T) {
+	tests := []struct {
+		name   string
+		rotate bool
+		// wantReopenRotated tells whether closing and opening the portal gives a new passphrase
+		wantReopenRotated bool
+	}{
+		{name: "without rotation", rotate: false, wantReopenRotated: false},
+		{name: "with rotation", rotate: true, wantReopenRotated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			nm.StepInterval = testStepInterval
+			device := nm.AddWifiDevice("wlan0", []*fake.AccessPoint{fake.WPA2AccessPoint("home", 80)})
+			device.NoScanInAPMode = true
+			cfg := testConfig()
+			cfg.Passphrase = models.PassphraseRandom
+			cfg.RotatePassphrase = tt.rotate
+			cfg.PassphraseFile = filepath.Join(t.TempDir(), "passphrase")
+			c := newTestNetwork(t, nm, cfg)
+			err := c.CreateHotSpot()
+			if err != nil {
+				t.Fatalf("CreateHotSpot: %s", err.Error())
+			}
+			first := c.HotSpotPassphrase
+			if len(first) != passphraseLength {
+				t.Fatalf("passphrase %q, want %d characters", first, passphraseLength)
+			}
+
+			// the hotspot is dropped and restored for the scan
+			_, err = c.Scan(context.Background())
+			if err != nil {
+				t.Fatalf("Scan: %s", err.Error())
+			}
+			if runs := len(apActivations(nm)); runs != 2 {
+				t.Fatalf("portal activated %d times, want 2", runs)
+			}
+			if c.HotSpotPassphrase != first {
+				t.Errorf("passphrase changed by the scan")
+			}
+			assertPassphraseFile(t, cfg.PassphraseFile, first)
+
+			err = c.CloseHotSpot()
+			if err != nil {
+				t.Fatalf("CloseHotSpot: %s", err.Error())
+			}
+			err = c.CreateHotSpot()
+			if err != nil {
+				t.Fatalf("CreateHotSpot: %s", err.Error())
+			}
+			if rotated := c.HotSpotPassphrase != first; rotated != tt.wantReopenRotated {
+				t.Errorf("passphrase rotated %t on reopening the portal, want %t", rotated, tt.wantReopenRotated)
+			}
+			assertPassphraseFile(t, cfg.PassphraseFile, c.HotSpotPassphrase)
+			psk, _ := apActivations(nm)[2].Connection.Settings["802-11-wireless-security"]["psk"].(string)
+			if psk != c.HotSpotPassphrase {
+				t.Errorf("profile psk %q, want %q", psk, c.HotSpotPassphrase)
+			}
+		})
+	}
+}
+
+func assertPassphraseFile(t *testing.T, path string, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the passphrase file: %s", err.Error())
+	}
+	if got := strings.TrimSuffix(string(data), "\n"); got != want {
+		t.Errorf("passphrase file holds %q, want %q", got, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %s", err.Error())
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("passphrase file mode %o, want 600", mode)
+	}
+}
@@ -0,0 +1,66 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/qrcode"
+)
+
+// qrCodeFileScale is the width of a module of the QR code file, in pixels for PNG
+const qrCodeFileScale = 8
+
+// GetHotSpot returns the SSID, security and passphrase of the active hotspot
+func (c *Config) GetHotSpot() (hotspot models.HotSpot, err error) {
+	c.hotSpotMu.Lock()
+	defer c.hotSpotMu.Unlock()
+	return c.hotSpot()
+}
+
+// hotSpot returns the active hotspot, the caller holds hotSpotMu
+func (c *Config) hotSpot() (hotspot models.HotSpot, err error) {
+	if !c.isHotSpotCreated {
+		err = models.ErrHotSpotNotActive
+		return
+	}
+	hotspot = models.HotSpot{
+		SSID:       c.HotSpotSSID,
+		Security:   c.Cfg.Fetch().PortalSecurity(),
+		Passphrase: c.HotSpotPassphrase,
+	}
+	return
+}
+
+// writeHotSpotQRCode writes the QR code for joining the active hotspot to the QR code file, when configured.
+// It holds the passphrase so the file is only readable by its owner. The caller holds hotSpotMu.
+func (c *Config) writeHotSpotQRCode() (err error) {
+	cfg := c.Cfg.Fetch()
+	if cfg.QRCodeFile == "" {
+		return
+	}
+	hotspot, err := c.hotSpot()
+	if err != nil {
+		return
+	}
+	code, err := qrcode.Encode([]byte(hotspot.WifiQRText()))
+	if err != nil {
+		err = fmt.Errorf("found error on qrcode.Encode [%s]", err.Error())
+		return
+	}
+	var image []byte
+	if cfg.IsSVGQRCodeFile() {
+		image = code.SVG(qrCodeFileScale)
+	} else {
+		image, err = code.PNG(qrCodeFileScale)
+		if err != nil {
+			err = fmt.Errorf("found error on PNG [%s]", err.Error())
+			return
+		}
+	}
+	err = writePrivateFile(cfg.QRCodeFile, image)
+	if err != nil {
+		return
+	}
+	c.Log.Info(fmt.Sprintf("writeHotSpotQRCode - QR code written to %s", cfg.QRCodeFile))
+	return
+}
@@ -0,0 +1,50 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// hotSpotRadio returns the band and channel of the hotspot, the channel is 0 when NetworkManager chooses it.
+// 5 GHz falls back to 2.4 GHz when the device reports it cannot use the band, an auto channel is picked
+// within the resulting band.
+func (c *Config) hotSpotRadio() (band string, channel uint32, err error) {
+	cfg := c.Cfg.Fetch()
+	channel, err = cfg.HotspotChannel()
+	if err != nil {
+		return
+	}
+	caps, err := c.WifiDevice.GetPropertyWirelessCapabilities()
+	if err != nil {
+		err = fmt.Errorf("found error on GetPropertyWirelessCapabilities [%s]", err.Error())
+		return
+	}
+	// the band flags are only meaningful once the driver reported them
+	bandsKnown := caps&models.WifiDeviceCapFreqValid.U32() != 0
+	supports5GHz := caps&models.WifiDeviceCapFreq5GHz.U32() != 0
+
+	band = cfg.Band
+	if band == models.HotspotBandAuto {
+		switch {
+		case channel != 0:
+			band = models.ChannelBand(channel)
+		case bandsKnown && supports5GHz:
+			band = models.HotspotBandA
+		default:
+			band = models.HotspotBandBG
+		}
+	}
+	if band == models.HotspotBandA && bandsKnown && !supports5GHz {
+		c.Log.Warn(fmt.Sprintf("hotSpotRadio - %s does not support 5 GHz, falling back to 2.4 GHz", c.WifiInterface))
+		band = models.HotspotBandBG
+		if channel != 0 {
+			c.Log.Warn(fmt.Sprintf("hotSpotRadio - channel %d ignored, NetworkManager chooses the 2.4 GHz channel", channel))
+			channel = 0
+		}
+	}
+	if cfg.IsAutoChannel() {
+		channel = c.leastCongestedChannel(band)
+	}
+	return
+}
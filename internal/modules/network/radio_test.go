@@ -0,0 +1,79 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+func TestHotSpotRadio(t *testing.T) {
+	dualBand := fake.DefaultCapabilities
+	singleBand := (models.WifiDeviceCapAP | models.WifiDeviceCapFreqValid | models.WifiDeviceCapFreq2GHz).U32()
+	// the band flags are not reported by every driver
+	bandsUnknown := models.WifiDeviceCapAP.U32()
+	tests := []struct {
+		name    string
+		band    string
+		channel string
+		caps    uint32
+		// scan is read before the radio is chosen, as when creating the hotspot
+		scan        []*fake.AccessPoint
+		wantBand    string
+		wantChannel uint32
+		wantErr     bool
+	}{
+		{name: "2.4 GHz", band: models.HotspotBandBG, caps: dualBand, wantBand: models.HotspotBandBG},
+		{name: "5 GHz", band: models.HotspotBandA, caps: dualBand, wantBand: models.HotspotBandA},
+		{name: "5 GHz without Freq5GHz", band: models.HotspotBandA, caps: singleBand, wantBand: models.HotspotBandBG},
+		{name: "5 GHz channel without Freq5GHz", band: models.HotspotBandA, channel: "36", caps: singleBand, wantBand: models.HotspotBandBG},
+		{name: "5 GHz with unknown bands", band: models.HotspotBandA, caps: bandsUnknown, wantBand: models.HotspotBandA},
+		{name: "auto band with Freq5GHz", band: models.HotspotBandAuto, caps: dualBand, wantBand: models.HotspotBandA},
+		{name: "auto band without Freq5GHz", band: models.HotspotBandAuto, caps: singleBand, wantBand: models.HotspotBandBG},
+		{name: "auto band with unknown bands", band: models.HotspotBandAuto, caps: bandsUnknown, wantBand: models.HotspotBandBG},
+		{name: "2.4 GHz channel selects the band", band: models.HotspotBandAuto, channel: "6", caps: dualBand, wantBand: models.HotspotBandBG, wantChannel: 6},
+		{name: "5 GHz channel selects the band", band: models.HotspotBandAuto, channel: "44", caps: dualBand, wantBand: models.HotspotBandA, wantChannel: 44},
+		{name: "5 GHz channel selected without Freq5GHz", band: models.HotspotBandAuto, channel: "44", caps: singleBand, wantBand: models.HotspotBandBG},
+		{
+			name:        "auto channel in the auto band",
+			band:        models.HotspotBandAuto,
+			channel:     "auto",
+			caps:        dualBand,
+			scan:        []*fake.AccessPoint{testAccessPoint(36, 80), testAccessPoint(6, 80)},
+			wantBand:    models.HotspotBandA,
+			wantChannel: 40,
+		},
+		{
+			name:        "auto channel after falling back to 2.4 GHz",
+			band:        models.HotspotBandA,
+			channel:     "auto",
+			caps:        singleBand,
+			scan:        []*fake.AccessPoint{testAccessPoint(36, 80), testAccessPoint(1, 80)},
+			wantBand:    models.HotspotBandBG,
+			wantChannel: 6,
+		},
+		{name: "invalid channel", band: models.HotspotBandAuto, channel: "15", caps: dualBand, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			nm.AddWifiDevice("wlan0", tt.scan).Capabilities = tt.caps
+			cfg := testConfig()
+			cfg.Band, cfg.Channel = tt.band, tt.channel
+			c := newTestNetwork(t, nm, cfg)
+			aps, err := c.readAccessPoints()
+			if err != nil {
+				t.Fatalf("readAccessPoints: %s", err.Error())
+			}
+			c.updateAccessPoints(aps)
+
+			band, channel, err := c.hotSpotRadio()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("hotSpotRadio returned %v, want error %t", err, tt.wantErr)
+			}
+			if band != tt.wantBand || channel != tt.wantChannel {
+				t.Errorf("band %q channel %d, want %q channel %d", band, channel, tt.wantBand, tt.wantChannel)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package network
+
+import (
+	"time"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// deviceReasonCodes maps the device state reasons of a failed activation to error codes
+var deviceReasonCodes = map[gonetworkmanager.NmDeviceStateReason]models.ConnectErrorCode{
+	gonetworkmanager.NmDeviceStateReasonNoSecrets:              models.ConnectErrorAuthFailed,
+	gonetworkmanager.NmDeviceStateReasonSupplicantDisconnect:   models.ConnectErrorAuthFailed,
+	gonetworkmanager.NmDeviceStateReasonSupplicantConfigFailed: models.ConnectErrorAuthFailed,
+	gonetworkmanager.NmDeviceStateReasonSupplicantFailed:       models.ConnectErrorAuthFailed,
+	gonetworkmanager.NmDeviceStateReasonSupplicantTimeout:      models.ConnectErrorAuthFailed,
+	gonetworkmanager.NmDeviceStateReasonIpConfigUnavailable:    models.ConnectErrorDHCPFailed,
+	gonetworkmanager.NmDeviceStateReasonIpConfigExpired:        models.ConnectErrorDHCPFailed,
+	gonetworkmanager.NmDeviceStateReasonDhcpStartFailed:        models.ConnectErrorDHCPFailed,
+	gonetworkmanager.NmDeviceStateReasonDhcpError:              models.ConnectErrorDHCPFailed,
+	gonetworkmanager.NmDeviceStateReasonDhcpFailed:             models.ConnectErrorDHCPFailed,
+	gonetworkmanager.NmDeviceStateReasonSsidNotFound:           models.ConnectErrorAPNotFound,
+}
+
+// activeConnectionReasonCodes maps the active connection state reasons of a failed activation to error codes
+var activeConnectionReasonCodes = map[gonetworkmanager.NmActiveConnectionStateReason]models.ConnectErrorCode{
+	gonetworkmanager.NmActiveConnectionStateReasonNoSecrets:       models.ConnectErrorAuthFailed,
+	gonetworkmanager.NmActiveConnectionStateReasonLoginFailed:     models.ConnectErrorAuthFailed,
+	gonetworkmanager.NmActiveConnectionStateReasonIpConfigInvalid: models.ConnectErrorDHCPFailed,
+	gonetworkmanager.NmActiveConnectionStateReasonConnectTimeout:  models.ConnectErrorTimeout,
+}
+
+// deviceReasonGrace bounds the wait for the device failure once the connection is deactivated,
+// both are signalled separately and may be received in any order
+const deviceReasonGrace = 500 * time.Millisecond
+
+// activationFailure collects the reasons NetworkManager gave while an activation failed
+type activationFailure struct {
+	acReason        gonetworkmanager.NmActiveConnectionStateReason
+	deviceReason    gonetworkmanager.NmDeviceStateReason
+	hasDeviceReason bool
+}
+
+// collectDeviceReason waits up to deviceReasonGrace for the device failure and picks up its reason
+func (f *activationFailure) collectDeviceReason(changes <-chan interfaces.DeviceStateChange) {
+	timer := time.NewTimer(deviceReasonGrace)
+	defer timer.Stop()
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				return
+			}
+			if change.State == gonetworkmanager.NmDeviceStateFailed {
+				f.deviceReason = change.Reason
+				f.hasDeviceReason = true
+				return
+			}
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+// err returns the error matching the reasons, the device reason is the most specific one
+func (f activationFailure) err() *models.ConnectError {
+	if code, ok := deviceReasonCodes[f.deviceReason]; ok && f.hasDeviceReason {
+		return models.NewConnectError(code, "device state reason %d", f.deviceReason)
+	}
+	if code, ok := activeConnectionReasonCodes[f.acReason]; ok {
+		return models.NewConnectError(code, "active connection state reason %d", f.acReason)
+	}
+	if f.hasDeviceReason {
+		return models.NewConnectError(models.ConnectErrorActivationFailed, "active connection state reason %d, device state reason %d", f.acReason, f.deviceReason)
+	}
+	return models.NewConnectError(models.ConnectErrorActivationFailed, "active connection state reason %d", f.acReason)
+}
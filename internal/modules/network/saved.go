@@ -0,0 +1,161 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// secretSettings are the settings holding secrets, GetSettings leaves them out
+var secretSettings = []string{"802-11-wireless-security", "802-1x"}
+
+// keyMgmtSecurity maps the key management of a wireless profile to its security
+var keyMgmtSecurity = map[string]models.SECURITY{
+	"none":                models.WEP,
+	"wpa-psk":             models.WPA2,
+	"sae":                 models.SAE,
+	"owe":                 models.OWE,
+	"wpa-eap":             models.ENTERPRISE,
+	"wpa-eap-suite-b-192": models.ENTERPRISE192,
+}
+
+// GetSavedNetworks returns the wireless profiles stored by NetworkManager, highest priority first.
+// The profile of the portal itself is left out.
+func (c *Config) GetSavedNetworks() (networks []models.SavedNetwork, err error) {
+	conns, err := c.NetworkManager.ListConnections()
+	if err != nil {
+		err = fmt.Errorf("GetSavedNetworks - found error on ListConnections [%s]", err.Error())
+		c.Log.Error(err.Error())
+		return
+	}
+	networks = []models.SavedNetwork{}
+	for _, conn := range conns {
+		var sett gonetworkmanager.ConnectionSettings
+		sett, err = conn.GetSettings()
+		if err != nil {
+			err = fmt.Errorf("GetSavedNetworks - found error on GetSettings [%s]", err.Error())
+			c.Log.Error(err.Error())
+			return
+		}
+		if network, ok := toSavedNetwork(sett); ok {
+			networks = append(networks, network)
+		}
+	}
+	sort.SliceStable(networks, func(i, j int) bool {
+		return networks[i].Priority > networks[j].Priority
+	})
+	return
+}
+
+// ForgetSavedNetwork deletes the wireless profile with the given UUID
+func (c *Config) ForgetSavedNetwork(id string) (err error) {
+	conn, _, err := c.findSavedNetwork(id)
+	if err != nil {
+		return
+	}
+	err = conn.Delete()
+	if err != nil {
+		err = fmt.Errorf("ForgetSavedNetwork - found error on Delete [%s]", err.Error())
+		c.Log.Error(err.Error())
+		return
+	}
+	c.Log.Info(fmt.Sprintf("ForgetSavedNetwork - deleted profile %s", id))
+	return
+}
+
+// SetSavedNetworkPriority changes the autoconnect priority of the wireless profile with the given UUID
+func (c *Config) SetSavedNetworkPriority(id string, priority int32) (err error) {
+	conn, sett, err := c.findSavedNetwork(id)
+	if err != nil {
+		return
+	}
+	// Update replaces the whole profile, the secrets have to be sent back with it
+	for _, name := range secretSettings {
+		if _, ok := sett[name]; !ok {
+			continue
+		}
+		var secrets gonetworkmanager.ConnectionSettings
+		secrets, err = conn.GetSecrets(name)
+		if err != nil {
+			err = fmt.Errorf("SetSavedNetworkPriority - found error on GetSecrets [%s]", err.Error())
+			c.Log.Error(err.Error())
+			return
+		}
+		for key, value := range secrets[name] {
+			sett[name][key] = value
+		}
+	}
+	// the deprecated address and route lists duplicate address-data and route-data
+	for _, name := range []string{"ipv4", "ipv6"} {
+		delete(sett[name], "addresses")
+		delete(sett[name], "routes")
+	}
+	sett["connection"]["autoconnect-priority"] = priority
+	err = conn.Update(sett)
+	if err != nil {
+		err = fmt.Errorf("SetSavedNetworkPriority - found error on Update [%s]", err.Error())
+		c.Log.Error(err.Error())
+		return
+	}
+	c.Log.Info(fmt.Sprintf("SetSavedNetworkPriority - profile %s has priority %d", id, priority))
+	return
+}
+
+// findSavedNetwork returns the wireless profile with the given UUID and its settings
+func (c *Config) findSavedNetwork(id string) (conn interfaces.Connection, sett gonetworkmanager.ConnectionSettings, err error) {
+	conns, err := c.NetworkManager.ListConnections()
+	if err != nil {
+		err = fmt.Errorf("found error on ListConnections [%s]", err.Error())
+		c.Log.Error(err.Error())
+		return
+	}
+	for _, conn = range conns {
+		sett, err = conn.GetSettings()
+		if err != nil {
+			err = fmt.Errorf("found error on GetSettings [%s]", err.Error())
+			c.Log.Error(err.Error())
+			return
+		}
+		if network, ok := toSavedNetwork(sett); ok && network.ID == id {
+			return
+		}
+	}
+	err = models.ErrSavedNetworkNotFound
+	return
+}
+
+// toSavedNetwork describes a station wireless profile, ok is false for any other profile
+func toSavedNetwork(sett gonetworkmanager.ConnectionSettings) (network models.SavedNetwork, ok bool) {
+	wireless, isWireless := sett["802-11-wireless"]
+	if !isWireless {
+		return
+	}
+	if mode, _ := wireless["mode"].(string); mode == "ap" {
+		return
+	}
+	ssid, _ := wireless["ssid"].([]byte)
+	network.ID, _ = sett["connection"]["uuid"].(string)
+	network.Name, _ = sett["connection"]["id"].(string)
+	network.SSID = string(ssid)
+	network.Hidden, _ = wireless["hidden"].(bool)
+	network.Autoconnect = true
+	if autoconnect, set := sett["connection"]["autoconnect"].(bool); set {
+		network.Autoconnect = autoconnect
+	}
+	network.Priority, _ = sett["connection"]["autoconnect-priority"].(int32)
+	if timestamp, _ := sett["connection"]["timestamp"].(uint64); timestamp > 0 {
+		lastUsed := time.Unix(int64(timestamp), 0).UTC()
+		network.LastUsed = &lastUsed
+	}
+	security := models.NONE
+	if keyMgmt, set := sett["802-11-wireless-security"]["key-mgmt"].(string); set {
+		security = keyMgmtSecurity[keyMgmt]
+	}
+	network.Security = security.String()
+	ok = true
+	return
+}
@@ -0,0 +1,151 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+// newSavedNetworks returns a network module with two client profiles, the portal profile and a wired profile
+func newSavedNetworks(t *testing.T) (*Config, *fake.NetworkManager) {
+	t.Helper()
+	nm := fake.NewNetworkManager()
+	nm.AddWifiDevice("wlan0")
+	nm.AddConnection(gonetworkmanager.ConnectionSettings{
+		"connection":               {"id": "home", "uuid": "home-uuid", "type": "802-11-wireless", "timestamp": uint64(1700000000)},
+		"802-11-wireless":          {"ssid": []byte("home"), "mode": "infrastructure", "security": "802-11-wireless-security"},
+		"802-11-wireless-security": {"key-mgmt": "wpa-psk", "psk": "home-secret"},
+		"ipv4": {
+			"method":       "manual",
+			"address-data": []map[string]interface{}{{"address": "192.168.1.20", "prefix": uint32(24)}},
+			"addresses":    [][]uint32{{0x1401a8c0, 24, 0x0101a8c0}},
+			"routes":       [][]uint32{},
+		},
+	})
+	nm.AddConnection(gonetworkmanager.ConnectionSettings{
+		"connection":               {"id": "office", "uuid": "office-uuid", "type": "802-11-wireless", "autoconnect": false, "autoconnect-priority": int32(5)},
+		"802-11-wireless":          {"ssid": []byte("office"), "mode": "infrastructure", "hidden": true, "security": "802-11-wireless-security"},
+		"802-11-wireless-security": {"key-mgmt": "wpa-eap"},
+		"802-1x":                   {"eap": []string{"peap"}, "identity": "user", "password": "user-secret"},
+	})
+	nm.AddConnection(gonetworkmanager.ConnectionSettings{
+		"connection":      {"id": "WiFi Connect", "uuid": "portal-uuid", "type": "802-11-wireless", "autoconnect-priority": int32(10)},
+		"802-11-wireless": {"ssid": []byte("WiFi Connect"), "mode": "ap"},
+	})
+	nm.AddConnection(gonetworkmanager.ConnectionSettings{
+		"connection": {"id": "wired", "uuid": "wired-uuid", "type": "802-3-ethernet"},
+	})
+	return newTestNetwork(t, nm, testConfig()), nm
+}
+
+// savedProfile returns the settings stored by the fake for the profile, nil once deleted
+func savedProfile(nm *fake.NetworkManager, id string) gonetworkmanager.ConnectionSettings {
+	for _, conn := range nm.Saved {
+		if uuid, _ := conn.Settings["connection"]["uuid"].(string); uuid == id {
+			return conn.Settings
+		}
+	}
+	return nil
+}
+
+func TestGetSavedNetworks(t *testing.T) {
+	c, _ := newSavedNetworks(t)
+	networks, err := c.GetSavedNetworks()
+	if err != nil {
+		t.Fatalf("GetSavedNetworks: %s", err.Error())
+	}
+	if len(networks) != 2 || networks[0].ID != "office-uuid" || networks[1].ID != "home-uuid" {
+		t.Fatalf("saved networks %+v, want office then home without the portal and wired profiles", networks)
+	}
+	office, home := networks[0], networks[1]
+	if office.SSID != "office" || !office.Hidden || office.Autoconnect || office.Priority != 5 ||
+		office.Security != models.ENTERPRISE.String() || office.LastUsed != nil {
+		t.Errorf("office %+v", office)
+	}
+	if home.SSID != "home" || home.Hidden || !home.Autoconnect || home.Priority != 0 || home.Security != models.WPA2.String() {
+		t.Errorf("home %+v", home)
+	}
+	if home.LastUsed == nil || home.LastUsed.Unix() != 1700000000 {
+		t.Errorf("home last used %v, want 1700000000", home.LastUsed)
+	}
+}
+
+func TestSetSavedNetworkPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		// wantSecret is the setting and key of the secret kept by the update
+		wantSecret [2]string
+		wantValue  string
+		wantErr    error
+	}{
+		{name: "psk network", id: "home-uuid", wantSecret: [2]string{"802-11-wireless-security", "psk"}, wantValue: "home-secret"},
+		{name: "enterprise network", id: "office-uuid", wantSecret: [2]string{"802-1x", "password"}, wantValue: "user-secret"},
+		{name: "unknown profile", id: "unknown-uuid", wantErr: models.ErrSavedNetworkNotFound},
+		{name: "portal profile", id: "portal-uuid", wantErr: models.ErrSavedNetworkNotFound},
+		{name: "wired profile", id: "wired-uuid", wantErr: models.ErrSavedNetworkNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, nm := newSavedNetworks(t)
+			err := c.SetSavedNetworkPriority(tt.id, 42)
+			if err != tt.wantErr {
+				t.Fatalf("SetSavedNetworkPriority returned %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if portal := savedProfile(nm, "portal-uuid"); portal["connection"]["autoconnect-priority"] != int32(10) {
+					t.Errorf("portal priority changed to %v", portal["connection"]["autoconnect-priority"])
+				}
+				return
+			}
+			sett := savedProfile(nm, tt.id)
+			if priority := sett["connection"]["autoconnect-priority"]; priority != int32(42) {
+				t.Errorf("priority %v, want 42", priority)
+			}
+			if value := sett[tt.wantSecret[0]][tt.wantSecret[1]]; value != tt.wantValue {
+				t.Errorf("%s.%s %v after the update, want %q", tt.wantSecret[0], tt.wantSecret[1], value, tt.wantValue)
+			}
+			for _, key := range []string{"addresses", "routes"} {
+				if _, ok := sett["ipv4"][key]; ok {
+					t.Errorf("deprecated ipv4.%s sent back", key)
+				}
+			}
+			if tt.id == "home-uuid" && sett["ipv4"]["address-data"] == nil {
+				t.Errorf("ipv4.address-data dropped")
+			}
+		})
+	}
+}
+
+func TestForgetSavedNetwork(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		wantErr error
+	}{
+		{name: "client profile", id: "home-uuid"},
+		{name: "unknown profile", id: "unknown-uuid", wantErr: models.ErrSavedNetworkNotFound},
+		{name: "portal profile", id: "portal-uuid", wantErr: models.ErrSavedNetworkNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, nm := newSavedNetworks(t)
+			err := c.ForgetSavedNetwork(tt.id)
+			if err != tt.wantErr {
+				t.Fatalf("ForgetSavedNetwork returned %v, want %v", err, tt.wantErr)
+			}
+			wantSaved := 4
+			if tt.wantErr == nil {
+				wantSaved = 3
+				if savedProfile(nm, tt.id) != nil {
+					t.Errorf("profile %s kept", tt.id)
+				}
+			}
+			if len(nm.Saved) != wantSaved {
+				t.Errorf("%d profiles saved, want %d", len(nm.Saved), wantSaved)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// scanTimeout bounds how long Scan waits for the device to report new results
+const scanTimeout = 20 * time.Second
+
+// Scan requests a fresh scan from the station device and merges the results into the access point cache.
+// Drivers which cannot scan while hosting an access point get the hotspot closed for the duration
+// of the scan and restored afterwards. When the scan does not complete in time, or the context is
+// cancelled, the results seen so far are returned and marked as partial.
+func (c *Config) Scan(ctx context.Context) (result models.ScanResult, err error) {
+	ctx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+
+	var lastScan int64
+	lastScan, err = c.StationDevice.GetPropertyLastScan()
+	if err != nil {
+		err = fmt.Errorf("Scan - found error on GetPropertyLastScan [%s]", err.Error())
+		c.Log.Error(err.Error())
+		return
+	}
+
+	restoreHotSpot := false
+	scanErr := c.StationDevice.RequestScan()
+	if scanErr != nil && !c.isDualRadio() {
+		// the hotspot stays down until restored, the portal must not be closed or opened meanwhile
+		c.hotSpotMu.Lock()
+		defer c.hotSpotMu.Unlock()
+		if c.isHotSpotCreated {
+			c.Log.Warn(fmt.Sprintf("Scan - device cannot scan while hosting the hotspot (%s), closing hotspot", scanErr.Error()))
+			err = c.closeHotSpot()
+			if err != nil {
+				return
+			}
+			restoreHotSpot = true
+			scanErr = c.StationDevice.RequestScan()
+		}
+	}
+
+	complete := false
+	if scanErr != nil {
+		c.Log.Error(fmt.Sprintf("Scan - found error on RequestScan [%s]", scanErr.Error()))
+	} else {
+		complete = c.waitForScan(ctx, lastScan)
+	}
+
+	if restoreHotSpot {
+		// createHotSpot reads the scan results before switching the device back to AP mode,
+		// the clients know the passphrase already
+		err = c.createHotSpot(ctx, false)
+		if err != nil {
+			return
+		}
+	} else {
+		var aps []AccessPoint
+		aps, err = c.readAccessPoints()
+		if err != nil {
+			err = fmt.Errorf("Scan - found error on readAccessPoints [%s]", err.Error())
+			c.Log.Error(err.Error())
+			return
+		}
+		c.updateAccessPoints(aps)
+	}
+
+	result.Partial = !complete
+	result.Networks, err = c.GetAccessPoint()
+	c.apMu.Lock()
+	result.ScannedAt = c.ScannedAt
+	c.apMu.Unlock()
+	c.Log.Info(fmt.Sprintf("Scan - %d networks, partial: %t", len(result.Networks), result.Partial))
+	return
+}
+
+// waitForScan waits until the device reports a scan newer than lastScan, returning false when the context ends first
+func (c *Config) waitForScan(ctx context.Context, lastScan int64) bool {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		current, err := c.StationDevice.GetPropertyLastScan()
+		if err != nil {
+			c.Log.Error(fmt.Sprintf("waitForScan - found error on GetPropertyLastScan [%s]", err.Error()))
+			return false
+		}
+		if current != lastScan {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			c.Log.Warn("waitForScan - scan did not complete in time, returning partial results")
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateAccessPoints merges scan results into the cache. Networks missing from the scan
+// are kept until they have not been seen for the configured scan TTL.
+func (c *Config) updateAccessPoints(aps []AccessPoint) {
+	ttl := time.Duration(c.Cfg.Fetch().ScanTTL) * time.Second
+	now := time.Now()
+
+	c.apMu.Lock()
+	defer c.apMu.Unlock()
+	seen := make(map[string]bool)
+	for _, a := range aps {
+		seen[a.SSID] = true
+	}
+	for _, a := range c.AccessPoints {
+		if !seen[a.SSID] && now.Sub(a.LastSeen) < ttl {
+			aps = append(aps, a)
+		}
+	}
+	sort.Slice(aps, func(i, j int) bool {
+		return aps[i].Strength > aps[j].Strength
+	})
+	c.AccessPoints = aps
+	c.ScannedAt = now
+}
@@ -0,0 +1,89 @@
+package network
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+func TestScanRestoresHotSpot(t *testing.T) {
+	home := fake.WPA2AccessPoint("home", 80)
+	tests := []struct {
+		name string
+		// scans are the results of the portal creation and of the scans following it
+		scans        [][]*fake.AccessPoint
+		cancelled    bool
+		wantNetworks int
+	}{
+		{
+			name:         "scan results",
+			scans:        [][]*fake.AccessPoint{{home}, {home, fake.OpenAccessPoint("cafe", 60)}},
+			wantNetworks: 2,
+		},
+		{
+			name:         "request cancelled without results",
+			scans:        [][]*fake.AccessPoint{{home}, {}},
+			cancelled:    true,
+			wantNetworks: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			nm.StepInterval = testStepInterval
+			device := nm.AddWifiDevice("wlan0", tt.scans...)
+			device.NoScanInAPMode = true
+			c := newTestNetwork(t, nm, testConfig())
+			err := c.CreateHotSpot()
+			if err != nil {
+				t.Fatalf("CreateHotSpot: %s", err.Error())
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+			// the web server reads the hotspot while it is dropped and restored
+			var readers sync.WaitGroup
+			stop := make(chan struct{})
+			for _, read := range []func(){
+				func() { c.GetHotSpot() },
+				func() { c.GetPortalClients() },
+			} {
+				readers.Add(1)
+				go func(read func()) {
+					defer readers.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+							read()
+						}
+					}
+				}(read)
+			}
+			result, err := c.Scan(ctx)
+			close(stop)
+			readers.Wait()
+			if err != nil {
+				t.Fatalf("Scan: %s", err.Error())
+			}
+			if len(result.Networks) != tt.wantNetworks {
+				t.Errorf("%d networks, want %d", len(result.Networks), tt.wantNetworks)
+			}
+			if _, err = c.GetHotSpot(); err != nil {
+				t.Errorf("hotspot not restored: %s", err.Error())
+			}
+			if _, err = c.GetPortalClients(); err != nil {
+				t.Errorf("GetPortalClients: %s", err.Error())
+			}
+			if runs := len(apActivations(nm)); runs != 2 {
+				t.Errorf("portal activated %d times, want 2", runs)
+			}
+		})
+	}
+}
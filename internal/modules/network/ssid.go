@@ -0,0 +1,155 @@
+package network
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// serialNumberFiles hold the serial number of the board on devicetree and DMI systems
+var serialNumberFiles = []string{"/sys/firmware/devicetree/base/serial-number", "/sys/class/dmi/id/product_serial"}
+
+// machineIDFiles hold the machine ID, the D-Bus one is used on systems without systemd
+var machineIDFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// machineIDLength is the number of characters of the machine ID used in the SSID
+const machineIDLength = 8
+
+var errNoSerialNumber = errors.New("no serial number found")
+
+// hotSpotSSID expands the placeholders of the portal SSID. A numeric suffix is appended
+// when another access point of the last scan already uses the SSID.
+func (c *Config) hotSpotSSID() (ssid string, err error) {
+	ssid = c.Cfg.Fetch().SSID
+	for _, placeholder := range models.SSIDPlaceholders {
+		if !strings.Contains(ssid, placeholder) {
+			continue
+		}
+		var value string
+		value, err = c.ssidPlaceholderValue(placeholder)
+		if err != nil {
+			err = fmt.Errorf("found error on expanding %s [%s]", placeholder, err.Error())
+			return
+		}
+		ssid = strings.ReplaceAll(ssid, placeholder, value)
+	}
+	if len(ssid) > models.MaxSSIDLength {
+		c.Log.Warn(fmt.Sprintf("hotSpotSSID - %q is longer than %d bytes, truncated", ssid, models.MaxSSIDLength))
+		ssid = truncateSSID(ssid, models.MaxSSIDLength)
+	}
+	base := ssid
+	for n := 2; c.isSSIDInRange(ssid); n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		ssid = truncateSSID(base, models.MaxSSIDLength-len(suffix)) + suffix
+	}
+	if ssid != base {
+		c.Log.Info(fmt.Sprintf("hotSpotSSID - %q is already used nearby, using %q", base, ssid))
+	}
+	return
+}
+
+// ssidPlaceholderValue returns the value of a placeholder of the portal SSID
+func (c *Config) ssidPlaceholderValue(placeholder string) (value string, err error) {
+	switch placeholder {
+	case "{hostname}":
+		return os.Hostname()
+	case "{mac4}":
+		var mac string
+		mac, err = c.WifiDevice.GetPropertyHwAddress()
+		if err != nil {
+			return
+		}
+		mac = strings.ToUpper(strings.ReplaceAll(mac, ":", ""))
+		if len(mac) < 4 {
+			err = fmt.Errorf("invalid MAC address %q", mac)
+			return
+		}
+		return mac[len(mac)-4:], nil
+	case "{serial}":
+		return readSerialNumber()
+	case "{machine-id}":
+		value, err = readFirstFile(machineIDFiles)
+		if err != nil {
+			return
+		}
+		if len(value) > machineIDLength {
+			value = value[:machineIDLength]
+		}
+		return
+	}
+	err = fmt.Errorf("unknown placeholder %s", placeholder)
+	return
+}
+
+// isSSIDInRange tells whether another access point of the last scan uses the SSID,
+// the portal itself may still be listed after it was closed and is left out
+func (c *Config) isSSIDInRange(ssid string) bool {
+	own, _ := c.WifiDevice.GetPropertyHwAddress()
+	c.apMu.Lock()
+	defer c.apMu.Unlock()
+	for _, ap := range c.AccessPoints {
+		if ap.SSID != ssid {
+			continue
+		}
+		if bssid, err := ap.AP.GetPropertyHWAddress(); err == nil && own != "" && strings.EqualFold(bssid, own) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// readSerialNumber returns the serial number of the board from the firmware, or from /proc/cpuinfo on older kernels
+func readSerialNumber() (serial string, err error) {
+	serial, err = readFirstFile(serialNumberFiles)
+	if err == nil {
+		return
+	}
+	file, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		err = errNoSerialNumber
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 2)
+		if len(fields) == 2 && strings.TrimSpace(fields[0]) == "Serial" && strings.TrimSpace(fields[1]) != "" {
+			return strings.TrimSpace(fields[1]), nil
+		}
+	}
+	err = errNoSerialNumber
+	return
+}
+
+// readFirstFile returns the trimmed content of the first readable, non-empty file
+func readFirstFile(paths []string) (value string, err error) {
+	for _, path := range paths {
+		data, readErr := os.ReadFile(path)
+		if readErr != nil {
+			continue
+		}
+		value = strings.TrimSpace(strings.Trim(string(data), "\x00"))
+		if value != "" {
+			return
+		}
+	}
+	err = fmt.Errorf("none of %s is readable", strings.Join(paths, ", "))
+	return
+}
+
+// truncateSSID cuts the SSID to at most length bytes without splitting a character
+func truncateSSID(ssid string, length int) string {
+	if len(ssid) <= length {
+		return ssid
+	}
+	for length > 0 && !utf8.RuneStart(ssid[length]) {
+		length--
+	}
+	return ssid[:length]
+}
@@ -0,0 +1,93 @@
+package network
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/modules/networkmanager/fake"
+)
+
+func TestTruncateSSID(t *testing.T) {
+	tests := []struct {
+		name   string
+		ssid   string
+		length int
+		want   string
+	}{
+		{"short", "WiFi Connect", 32, "WiFi Connect"},
+		{"ascii", strings.Repeat("a", 40), 32, strings.Repeat("a", 32)},
+		{"exact fit", strings.Repeat("é", 16), 32, strings.Repeat("é", 16)},
+		// 31 bytes of ASCII followed by a 2 byte character
+		{"two byte character across the limit", strings.Repeat("a", 31) + "é", 32, strings.Repeat("a", 31)},
+		// 11 characters of 3 bytes take 33 bytes
+		{"three byte characters", strings.Repeat("€", 11), 32, strings.Repeat("€", 10)},
+		{"four byte character across the limit", strings.Repeat("a", 30) + "📶", 32, strings.Repeat("a", 30)},
+		{"four byte characters", strings.Repeat("📶", 9), 32, strings.Repeat("📶", 8)},
+		{"room for a suffix", "Café " + strings.Repeat("€", 10), 29, "Café " + strings.Repeat("€", 7)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateSSID(tt.ssid, tt.length)
+			if got != tt.want {
+				t.Errorf("truncateSSID(%q, %d) = %q, want %q", tt.ssid, tt.length, got, tt.want)
+			}
+			if len(got) > tt.length || !utf8.ValidString(got) {
+				t.Errorf("truncateSSID(%q, %d) = %q, %d bytes, valid UTF-8 %t", tt.ssid, tt.length, got, len(got), utf8.ValidString(got))
+			}
+		})
+	}
+}
+
+func TestHotSpotSSID(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		scan     []*fake.AccessPoint
+		want     string
+	}{
+		{
+			name:     "mac placeholder",
+			template: "Setup {mac4}",
+			want:     "Setup BEEF",
+		},
+		{
+			name:     "truncated after expansion",
+			template: "Configuración del equipo {mac4} 📶",
+			want:     "Configuración del equipo BEEF ",
+		},
+		{
+			name:     "suffix within the limit",
+			template: "x" + strings.Repeat("€", 10),
+			scan:     []*fake.AccessPoint{fake.OpenAccessPoint("x"+strings.Repeat("€", 10), 50)},
+			want:     "x" + strings.Repeat("€", 9) + "-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := fake.NewNetworkManager()
+			device := nm.AddWifiDevice("wlan0", tt.scan)
+			device.HWAddress = "02:00:00:00:be:ef"
+			cfg := testConfig()
+			cfg.SSID = tt.template
+			c := newTestNetwork(t, nm, cfg)
+			aps, err := c.readAccessPoints()
+			if err != nil {
+				t.Fatalf("readAccessPoints: %s", err.Error())
+			}
+			c.updateAccessPoints(aps)
+
+			ssid, err := c.hotSpotSSID()
+			if err != nil {
+				t.Fatalf("hotSpotSSID: %s", err.Error())
+			}
+			if ssid != tt.want {
+				t.Errorf("SSID %q, want %q", ssid, tt.want)
+			}
+			if len(ssid) > models.MaxSSIDLength || !utf8.ValidString(ssid) {
+				t.Errorf("SSID %q is %d bytes, valid UTF-8 %t", ssid, len(ssid), utf8.ValidString(ssid))
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package network
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+var errPortalSubnetPoolExhausted = errors.New("every /24 of the portal subnet pool is in use")
+
+// portalSubnet returns the subnet served on the portal. In auto mode a /24 of the pool is picked
+// which overlaps neither the addresses of the devices nor the ones of the saved profiles.
+func (c *Config) portalSubnet() (subnet models.PortalSubnet, err error) {
+	cfg := c.Cfg.Fetch()
+	if !cfg.IsAutoPortalNetwork() {
+		return cfg.PortalSubnet()
+	}
+	pool, err := cfg.PortalSubnetPool()
+	if err != nil {
+		return
+	}
+	used, err := c.usedIP4Networks()
+	if err != nil {
+		return
+	}
+	subnet, err = selectPortalSubnet(pool, used)
+	if err != nil {
+		return
+	}
+	c.Log.Info(fmt.Sprintf("portalSubnet - selected %s, avoiding %v", subnet.Subnet, used))
+	return
+}
+
+// usedIP4Networks returns the IPv4 subnets of every device and of every saved profile with static addresses,
+// access point profiles left out
+func (c *Config) usedIP4Networks() (used []*net.IPNet, err error) {
+	used, err = c.NetworkManager.GetIP4Networks()
+	if err != nil {
+		err = fmt.Errorf("found error on GetIP4Networks [%s]", err.Error())
+		return
+	}
+	conns, err := c.NetworkManager.ListConnections()
+	if err != nil {
+		err = fmt.Errorf("found error on ListConnections [%s]", err.Error())
+		return
+	}
+	for _, conn := range conns {
+		sett, settErr := conn.GetSettings()
+		if settErr != nil {
+			c.Log.Warn(fmt.Sprintf("usedIP4Networks - found error on GetSettings [%s]", settErr.Error()))
+			continue
+		}
+		if mode, _ := sett["802-11-wireless"]["mode"].(string); mode == "ap" {
+			continue
+		}
+		used = append(used, addressDataNetworks(sett["ipv4"]["address-data"])...)
+	}
+	return
+}
+
+// addressDataNetworks returns the subnets of an address-data setting, as decoded from D-Bus or built in memory
+func addressDataNetworks(value interface{}) (networks []*net.IPNet) {
+	var entries []map[string]interface{}
+	switch addressData := value.(type) {
+	case []map[string]interface{}:
+		entries = addressData
+	case []map[string]dbus.Variant:
+		for _, variants := range addressData {
+			entry := make(map[string]interface{})
+			for key, variant := range variants {
+				entry[key] = variant.Value()
+			}
+			entries = append(entries, entry)
+		}
+	}
+	for _, entry := range entries {
+		address, _ := entry["address"].(string)
+		prefix, _ := entry["prefix"].(uint32)
+		_, network, err := net.ParseCIDR(fmt.Sprintf("%s/%d", address, prefix))
+		if err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return
+}
+
+// selectPortalSubnet returns the first /24 of the pool overlapping none of the used networks
+func selectPortalSubnet(pool []*net.IPNet, used []*net.IPNet) (subnet models.PortalSubnet, err error) {
+	for _, network := range pool {
+		base := binary.BigEndian.Uint32(network.IP.To4())
+		prefix, _ := network.Mask.Size()
+		for i := uint32(0); i < uint32(1)<<uint(24-prefix); i++ {
+			ip := make(net.IP, net.IPv4len)
+			binary.BigEndian.PutUint32(ip, base+i<<8)
+			candidate := &net.IPNet{IP: ip, Mask: net.CIDRMask(24, 32)}
+			if !overlapsAny(candidate, used) {
+				return models.NewPortalSubnet(candidate), nil
+			}
+		}
+	}
+	err = errPortalSubnetPoolExhausted
+	return
+}
+
+func overlapsAny(network *net.IPNet, others []*net.IPNet) bool {
+	for _, other := range others {
+		if network.Contains(other.IP) || other.Contains(network.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package network
+
+import (
+	"net"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func mustParseCIDRs(t *testing.T, cidrs ...string) (networks []*net.IPNet) {
+	t.Helper()
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatalf("ParseCIDR(%s): %s", cidr, err.Error())
+		}
+		networks = append(networks, network)
+	}
+	return
+}
+
+func TestSelectPortalSubnet(t *testing.T) {
+	tests := []struct {
+		name       string
+		pool       []string
+		used       []string
+		wantSubnet string
+		wantErr    error
+	}{
+		{
+			name:       "nothing in use",
+			pool:       []string{"192.168.42.0/24", "10.42.0.0/16"},
+			wantSubnet: "192.168.42.0/24",
+		},
+		{
+			name:       "first entry in use",
+			pool:       []string{"192.168.42.0/24", "10.42.0.0/16"},
+			used:       []string{"192.168.42.0/24"},
+			wantSubnet: "10.42.0.0/24",
+		},
+		{
+			name:       "smaller network in use",
+			pool:       []string{"192.168.42.0/24", "10.42.0.0/16"},
+			used:       []string{"192.168.42.128/25", "10.42.0.16/28"},
+			wantSubnet: "10.42.1.0/24",
+		},
+		{
+			name:       "larger network in use",
+			pool:       []string{"192.168.42.0/24", "10.42.0.0/16", "172.29.0.0/16"},
+			used:       []string{"192.168.0.0/16", "10.0.0.0/8"},
+			wantSubnet: "172.29.0.0/24",
+		},
+		{
+			name:    "pool exhausted",
+			pool:    []string{"192.168.42.0/24", "10.42.0.0/23"},
+			used:    []string{"192.168.42.0/24", "10.42.0.0/24", "10.42.1.0/24"},
+			wantErr: errPortalSubnetPoolExhausted,
+		},
+		{
+			name:    "empty pool",
+			wantErr: errPortalSubnetPoolExhausted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subnet, err := selectPortalSubnet(mustParseCIDRs(t, tt.pool...), mustParseCIDRs(t, tt.used...))
+			if err != tt.wantErr {
+				t.Fatalf("selectPortalSubnet returned error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if subnet.Subnet.String() != tt.wantSubnet {
+				t.Errorf("subnet %s, want %s", subnet.Subnet, tt.wantSubnet)
+			}
+			if !subnet.Subnet.Contains(subnet.Gateway) || !subnet.Subnet.Contains(subnet.DHCPStart) || !subnet.Subnet.Contains(subnet.DHCPEnd) {
+				t.Errorf("gateway %s or DHCP range %s-%s outside of %s", subnet.Gateway, subnet.DHCPStart, subnet.DHCPEnd, subnet.Subnet)
+			}
+		})
+	}
+}
+
+func TestAddressDataNetworks(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+	}{
+		{
+			name:  "built in memory",
+			value: []map[string]interface{}{{"address": "10.42.0.5", "prefix": uint32(24)}},
+			want:  []string{"10.42.0.0/24"},
+		},
+		{
+			name: "decoded from D-Bus",
+			value: []map[string]dbus.Variant{
+				{"address": dbus.MakeVariant("192.168.1.10"), "prefix": dbus.MakeVariant(uint32(16))},
+				{"address": dbus.MakeVariant("172.29.3.1"), "prefix": dbus.MakeVariant(uint32(24))},
+			},
+			want: []string{"192.168.0.0/16", "172.29.3.0/24"},
+		},
+		{
+			name:  "invalid address",
+			value: []map[string]interface{}{{"address": "10.42.0", "prefix": uint32(24)}},
+		},
+		{
+			name: "missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			networks := addressDataNetworks(tt.value)
+			if len(networks) != len(tt.want) {
+				t.Fatalf("networks %v, want %v", networks, tt.want)
+			}
+			for i, network := range networks {
+				if network.String() != tt.want[i] {
+					t.Errorf("network %d is %s, want %s", i, network, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,622 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/godbus/dbus/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
+)
+
+const (
+	nmPath              = dbus.ObjectPath(gonetworkmanager.NetworkManagerObjectPath)
+	settingsPath        = dbus.ObjectPath(gonetworkmanager.SettingsObjectPath)
+	propertiesInterface = "org.freedesktop.DBus.Properties"
+	stateChangedSignal  = "StateChanged"
+	nm80211ModeInfra    = uint32(2)
+	nmStateConnected    = uint32(70)
+)
+
+// settingSignatures holds the D-Bus signatures NetworkManager expects for the settings sent by this project
+var settingSignatures = map[string]map[string]string{
+	"connection": {
+		"id":                   "s",
+		"uuid":                 "s",
+		"type":                 "s",
+		"interface-name":       "s",
+		"autoconnect":          "b",
+		"autoconnect-priority": "i",
+		"timestamp":            "t",
+	},
+	"802-11-wireless": {
+		"ssid":     "ay",
+		"mode":     "s",
+		"band":     "s",
+		"channel":  "u",
+		"hidden":   "b",
+		"security": "s",
+	},
+	"802-11-wireless-security": {
+		"key-mgmt":     "s",
+		"psk":          "s",
+		"wep-key0":     "s",
+		"wep-key-type": "u",
+		"pmf":          "i",
+		"proto":        "as",
+		"pairwise":     "as",
+		"group":        "as",
+	},
+	"802-1x": {
+		"eap":                  "as",
+		"identity":             "s",
+		"anonymous-identity":   "s",
+		"password":             "s",
+		"phase2-auth":          "s",
+		"ca-cert":              "ay",
+		"domain-suffix-match":  "s",
+		"client-cert":          "ay",
+		"private-key":          "ay",
+		"private-key-password": "s",
+	},
+	"ipv4": {
+		"method":          "s",
+		"address-data":    "aa{sv}",
+		"gateway":         "s",
+		"dns":             "au",
+		"dns-search":      "as",
+		"ignore-auto-dns": "b",
+	},
+	"ipv6": {
+		"method":          "s",
+		"address-data":    "aa{sv}",
+		"gateway":         "s",
+		"dns":             "aay",
+		"dns-search":      "as",
+		"ignore-auto-dns": "b",
+		"addr-gen-mode":   "i",
+	},
+}
+
+// Service exposes a fake NetworkManager on D-Bus under the org.freedesktop.NetworkManager name,
+// implementing the objects and properties read by gonetworkmanager
+type Service struct {
+	Log *logrus.Logger
+	NM  *NetworkManager
+	// Record receives the settings of every AddAndActivateConnection call as a JSON line
+	Record io.Writer
+
+	conn    *dbus.Conn
+	mu      sync.Mutex
+	paths   map[interface{}]dbus.ObjectPath
+	objects map[dbus.ObjectPath]interface{}
+	count   map[string]int
+}
+
+// properties implements org.freedesktop.DBus.Properties on top of getter functions
+type properties map[string]map[string]func() interface{}
+
+// NewService returns access to this module
+func NewService(l *logrus.Logger, nm *NetworkManager) *Service {
+	return &Service{
+		Log:     l,
+		NM:      nm,
+		paths:   make(map[interface{}]dbus.ObjectPath),
+		objects: make(map[dbus.ObjectPath]interface{}),
+		count:   make(map[string]int),
+	}
+}
+
+// Start exports the NetworkManager objects on the connection and claims the NetworkManager bus name
+func (s *Service) Start(conn *dbus.Conn) (err error) {
+	s.conn = conn
+	err = s.exportNetworkManager()
+	if err != nil {
+		return
+	}
+	err = s.exportSettings()
+	if err != nil {
+		return
+	}
+	reply, err := conn.RequestName(gonetworkmanager.NetworkManagerInterface, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		err = fmt.Errorf("found error on RequestName [%s]", err.Error())
+		return
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		err = fmt.Errorf("name %s already taken", gonetworkmanager.NetworkManagerInterface)
+		return
+	}
+	s.Log.Info(fmt.Sprintf("fake NetworkManager running as %s", gonetworkmanager.NetworkManagerInterface))
+	return
+}
+
+func (s *Service) exportNetworkManager() (err error) {
+	methods := map[string]interface{}{
+		"GetDevices":               s.getDevices,
+		"GetAllDevices":            s.getDevices,
+		"AddAndActivateConnection": s.addAndActivateConnection,
+		"DeactivateConnection":     s.deactivateConnection,
+		"CheckConnectivity": func() (uint32, *dbus.Error) {
+			connectivity, _ := s.NM.GetPropertyConnectivity()
+			return uint32(connectivity), nil
+		},
+	}
+	err = s.conn.ExportMethodTable(methods, nmPath, gonetworkmanager.NetworkManagerInterface)
+	if err != nil {
+		return
+	}
+	changes, err := s.NM.SubscribeConnectivity(context.Background())
+	if err != nil {
+		return
+	}
+	go func() {
+		for connectivity := range changes {
+			changed := map[string]dbus.Variant{"Connectivity": dbus.MakeVariant(uint32(connectivity))}
+			s.emit(nmPath, propertiesInterface+".PropertiesChanged", gonetworkmanager.NetworkManagerInterface, changed, []string{})
+		}
+	}()
+	props := properties{
+		gonetworkmanager.NetworkManagerInterface: {
+			"Devices":           func() interface{} { devices, _ := s.getDevices(); return devices },
+			"AllDevices":        func() interface{} { devices, _ := s.getDevices(); return devices },
+			"ActiveConnections": s.activeConnections,
+			"NetworkingEnabled": func() interface{} { return true },
+			"WirelessEnabled":   func() interface{} { return true },
+			"Startup":           func() interface{} { return false },
+			"Version":           func() interface{} { return "fake" },
+			"State":             func() interface{} { return nmStateConnected },
+			"Connectivity": func() interface{} {
+				connectivity, _ := s.NM.GetPropertyConnectivity()
+				return uint32(connectivity)
+			},
+		},
+	}
+	return s.conn.Export(props, nmPath, propertiesInterface)
+}
+
+func (s *Service) exportSettings() (err error) {
+	methods := map[string]interface{}{
+		"ListConnections": s.listConnections,
+	}
+	err = s.conn.ExportMethodTable(methods, settingsPath, gonetworkmanager.SettingsInterface)
+	if err != nil {
+		return
+	}
+	props := properties{
+		gonetworkmanager.SettingsInterface: {
+			"Connections": func() interface{} { conns, _ := s.listConnections(); return conns },
+			"CanModify":   func() interface{} { return true },
+			"Hostname":    func() interface{} { return "fake" },
+		},
+	}
+	return s.conn.Export(props, settingsPath, propertiesInterface)
+}
+
+func (s *Service) getDevices() (paths []dbus.ObjectPath, dErr *dbus.Error) {
+	devices, _ := s.NM.GetWifiDevices()
+	paths = []dbus.ObjectPath{}
+	for _, d := range devices {
+		paths = append(paths, s.path(d))
+	}
+	return
+}
+
+func (s *Service) listConnections() (paths []dbus.ObjectPath, dErr *dbus.Error) {
+	s.NM.mu.Lock()
+	saved := append([]*Connection{}, s.NM.Saved...)
+	s.NM.mu.Unlock()
+	paths = []dbus.ObjectPath{}
+	for _, conn := range saved {
+		paths = append(paths, s.path(conn))
+	}
+	return
+}
+
+func (s *Service) activeConnections() interface{} {
+	s.NM.mu.Lock()
+	active := append([]*ActiveConnection{}, s.NM.ActiveConnections...)
+	s.NM.mu.Unlock()
+	paths := []dbus.ObjectPath{}
+	for _, ac := range active {
+		state, _ := ac.GetPropertyState()
+		if state != gonetworkmanager.NmActiveConnectionStateDeactivated {
+			paths = append(paths, s.path(ac))
+		}
+	}
+	return paths
+}
+
+func (s *Service) addAndActivateConnection(settings map[string]map[string]dbus.Variant, device dbus.ObjectPath, specificObject dbus.ObjectPath) (connPath dbus.ObjectPath, acPath dbus.ObjectPath, dErr *dbus.Error) {
+	s.record(settings)
+	connection, err := s.decodeSettings(settings)
+	if err != nil {
+		s.Log.Error(fmt.Sprintf("AddAndActivateConnection - rejected settings: %s", err.Error()))
+		dErr = dbus.NewError("org.freedesktop.NetworkManager.Settings.Connection.InvalidProperty", []interface{}{err.Error()})
+		return
+	}
+	d, ok := s.lookup(device).(*WifiDevice)
+	if !ok {
+		dErr = dbus.NewError("org.freedesktop.NetworkManager.UnknownDevice", []interface{}{fmt.Sprintf("unknown device %s", device)})
+		return
+	}
+	var a interfaces.ActiveConnection
+	if specificObject == "/" || specificObject == "" {
+		a, err = s.NM.AddAndActivateConnection(connection, d)
+	} else {
+		ap, ok := s.lookup(specificObject).(*AccessPoint)
+		if !ok {
+			dErr = dbus.NewError("org.freedesktop.NetworkManager.UnknownConnection", []interface{}{fmt.Sprintf("unknown access point %s", specificObject)})
+			return
+		}
+		a, err = s.NM.AddAndActivateWirelessConnection(connection, d, ap)
+	}
+	if err != nil {
+		dErr = dbus.MakeFailedError(err)
+		return
+	}
+	ac := a.(*ActiveConnection)
+	connPath = s.path(ac.Connection)
+	acPath = s.path(ac)
+	return
+}
+
+func (s *Service) deactivateConnection(path dbus.ObjectPath) *dbus.Error {
+	ac, ok := s.lookup(path).(*ActiveConnection)
+	if !ok {
+		return dbus.NewError("org.freedesktop.NetworkManager.ConnectionNotActive", []interface{}{fmt.Sprintf("unknown active connection %s", path)})
+	}
+	err := s.NM.DeactivateConnection(ac)
+	if err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	return nil
+}
+
+// decodeSettings checks the settings against the signatures NetworkManager expects and unwraps the variants
+func (s *Service) decodeSettings(settings map[string]map[string]dbus.Variant) (connection map[string]map[string]interface{}, err error) {
+	connection = make(map[string]map[string]interface{})
+	for name, setting := range settings {
+		signatures, known := settingSignatures[name]
+		if !known {
+			s.Log.Warn(fmt.Sprintf("decodeSettings - unknown setting %s", name))
+		}
+		connection[name] = make(map[string]interface{})
+		for key, value := range setting {
+			if known {
+				if sig, ok := signatures[key]; !ok {
+					s.Log.Warn(fmt.Sprintf("decodeSettings - unknown property %s.%s", name, key))
+				} else if value.Signature().String() != sig {
+					err = fmt.Errorf("%s.%s: expected signature %s, got %s", name, key, sig, value.Signature().String())
+					return
+				}
+			}
+			connection[name][key] = value.Value()
+		}
+	}
+	return
+}
+
+func encodeSettings(settings gonetworkmanager.ConnectionSettings) map[string]map[string]dbus.Variant {
+	out := make(map[string]map[string]dbus.Variant)
+	for name, setting := range settings {
+		out[name] = make(map[string]dbus.Variant)
+		for key, value := range setting {
+			out[name][key] = dbus.MakeVariant(value)
+		}
+	}
+	return out
+}
+
+func (s *Service) record(settings map[string]map[string]dbus.Variant) {
+	rec := make(map[string]map[string]string)
+	for name, setting := range settings {
+		rec[name] = make(map[string]string)
+		for key, value := range setting {
+			rec[name][key] = value.String()
+		}
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		s.Log.Error(fmt.Sprintf("record - found error on Marshal: %s", err.Error()))
+		return
+	}
+	s.Log.Info(fmt.Sprintf("AddAndActivateConnection - %s", data))
+	if s.Record != nil {
+		s.Record.Write(append(data, '\n'))
+	}
+}
+
+func (s *Service) emit(p dbus.ObjectPath, name string, values ...interface{}) {
+	err := s.conn.Emit(p, name, values...)
+	if err != nil {
+		s.Log.Error(fmt.Sprintf("emit - found error on emitting %s on %s: %s", name, p, err.Error()))
+	}
+}
+
+func (s *Service) lookup(path dbus.ObjectPath) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[path]
+}
+
+// path returns the object path of the fake object, exporting it on first use
+func (s *Service) path(obj interface{}) dbus.ObjectPath {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.paths[obj]; ok {
+		return p
+	}
+	var kind string
+	switch obj.(type) {
+	case *WifiDevice:
+		kind = "Devices"
+	case *AccessPoint:
+		kind = "AccessPoint"
+	case *ActiveConnection:
+		kind = "ActiveConnection"
+	case *Connection:
+		kind = "Settings"
+	case *IP4Config:
+		kind = "IP4Config"
+	}
+	s.count[kind]++
+	p := dbus.ObjectPath(fmt.Sprintf("%s/%s/%d", nmPath, kind, s.count[kind]))
+	s.paths[obj] = p
+	s.objects[p] = obj
+
+	var err error
+	switch o := obj.(type) {
+	case *WifiDevice:
+		err = s.exportDevice(p, o)
+	case *AccessPoint:
+		err = s.exportAccessPoint(p, o)
+	case *ActiveConnection:
+		err = s.exportActiveConnection(p, o)
+	case *Connection:
+		err = s.exportConnection(p, o)
+	case *IP4Config:
+		err = s.exportIP4Config(p, o)
+	}
+	if err != nil {
+		s.Log.Error(fmt.Sprintf("path - found error on exporting %s: %s", p, err.Error()))
+	}
+	return p
+}
+
+func (s *Service) exportDevice(p dbus.ObjectPath, d *WifiDevice) (err error) {
+	accessPoints := func() ([]dbus.ObjectPath, *dbus.Error) {
+		aps, _ := d.GetAccessPoints()
+		paths := []dbus.ObjectPath{}
+		for _, ap := range aps {
+			paths = append(paths, s.path(ap))
+		}
+		return paths, nil
+	}
+	methods := map[string]interface{}{
+		"GetAccessPoints":    accessPoints,
+		"GetAllAccessPoints": accessPoints,
+		"RequestScan": func(options map[string]dbus.Variant) *dbus.Error {
+			err := d.RequestScan()
+			if err != nil {
+				return dbus.NewError("org.freedesktop.NetworkManager.Device.NotAllowed", []interface{}{err.Error()})
+			}
+			return nil
+		},
+	}
+	err = s.conn.ExportMethodTable(methods, p, gonetworkmanager.DeviceWirelessInterface)
+	if err != nil {
+		return
+	}
+	changes, err := d.SubscribeState(context.Background())
+	if err != nil {
+		return
+	}
+	go func() {
+		for change := range changes {
+			s.emit(p, gonetworkmanager.DeviceInterface+"."+stateChangedSignal, uint32(change.State), uint32(change.OldState), uint32(change.Reason))
+		}
+	}()
+	props := properties{
+		gonetworkmanager.DeviceInterface: {
+			"DeviceType":  func() interface{} { return uint32(gonetworkmanager.NmDeviceTypeWifi) },
+			"Interface":   func() interface{} { return d.Interface },
+			"IpInterface": func() interface{} { return d.Interface },
+			"Ip4Config": func() interface{} {
+				if d.IP4Config == nil {
+					return dbus.ObjectPath("/")
+				}
+				return s.path(d.IP4Config)
+			},
+			"Driver": func() interface{} { return "fake" },
+			"Managed": func() interface{} {
+				state, _ := d.GetPropertyState()
+				return state != gonetworkmanager.NmDeviceStateUnmanaged
+			},
+			"State": func() interface{} {
+				state, _ := d.GetPropertyState()
+				return uint32(state)
+			},
+			"AvailableConnections": func() interface{} {
+				conns, _ := d.GetPropertyAvailableConnections()
+				paths := []dbus.ObjectPath{}
+				for _, conn := range conns {
+					paths = append(paths, s.path(conn))
+				}
+				return paths
+			},
+		},
+		gonetworkmanager.DeviceWirelessInterface: {
+			"HwAddress":            func() interface{} { return d.HWAddress },
+			"PermHwAddress":        func() interface{} { return d.HWAddress },
+			"Mode":                 func() interface{} { return nm80211ModeInfra },
+			"WirelessCapabilities": func() interface{} { return d.Capabilities },
+			"LastScan": func() interface{} {
+				lastScan, _ := d.GetPropertyLastScan()
+				return lastScan
+			},
+		},
+	}
+	return s.conn.Export(props, p, propertiesInterface)
+}
+
+func (s *Service) exportIP4Config(p dbus.ObjectPath, c *IP4Config) (err error) {
+	props := properties{
+		gonetworkmanager.IP4ConfigInterface: {
+			"AddressData": func() interface{} {
+				addressData := []map[string]dbus.Variant{}
+				for _, address := range c.Addresses {
+					ip, network, err := net.ParseCIDR(address)
+					if err != nil {
+						continue
+					}
+					prefix, _ := network.Mask.Size()
+					addressData = append(addressData, map[string]dbus.Variant{
+						"address": dbus.MakeVariant(ip.String()),
+						"prefix":  dbus.MakeVariant(uint32(prefix)),
+					})
+				}
+				return addressData
+			},
+		},
+	}
+	return s.conn.Export(props, p, propertiesInterface)
+}
+
+func (s *Service) exportAccessPoint(p dbus.ObjectPath, ap *AccessPoint) (err error) {
+	props := properties{
+		gonetworkmanager.AccessPointInterface: {
+			"Flags":      func() interface{} { return ap.Flags },
+			"WpaFlags":   func() interface{} { return ap.WPAFlags },
+			"RsnFlags":   func() interface{} { return ap.RSNFlags },
+			"Ssid":       func() interface{} { return []byte(ap.SSID) },
+			"Strength":   func() interface{} { return ap.Strength },
+			"Frequency":  func() interface{} { return ap.Frequency },
+			"HwAddress":  func() interface{} { return ap.HWAddress },
+			"Mode":       func() interface{} { return nm80211ModeInfra },
+			"MaxBitrate": func() interface{} { return ap.MaxBitrate },
+			"LastSeen":   func() interface{} { return int32(-1) },
+		},
+	}
+	return s.conn.Export(props, p, propertiesInterface)
+}
+
+func (s *Service) exportActiveConnection(p dbus.ObjectPath, ac *ActiveConnection) (err error) {
+	changes, err := ac.SubscribeState(context.Background())
+	if err != nil {
+		return
+	}
+	go func() {
+		for change := range changes {
+			s.emit(p, gonetworkmanager.ActiveConnectionInterface+"."+gonetworkmanager.ActiveConnectionSignalStateChanged, uint32(change.State), uint32(change.Reason))
+		}
+	}()
+	setting := func(key string) func() interface{} {
+		return func() interface{} {
+			settings, _ := ac.Connection.GetSettings()
+			value, _ := settings["connection"][key].(string)
+			return value
+		}
+	}
+	props := properties{
+		gonetworkmanager.ActiveConnectionInterface: {
+			"Connection": func() interface{} { return s.path(ac.Connection) },
+			"SpecificObject": func() interface{} {
+				if ac.AccessPoint == nil {
+					return dbus.ObjectPath("/")
+				}
+				return s.path(ac.AccessPoint)
+			},
+			"Id":         setting("id"),
+			"Uuid":       setting("uuid"),
+			"Type":       setting("type"),
+			"Devices":    func() interface{} { return []dbus.ObjectPath{s.path(ac.Device)} },
+			"StateFlags": func() interface{} { return uint32(0) },
+			"Default":    func() interface{} { return false },
+			"Vpn":        func() interface{} { return false },
+			"State": func() interface{} {
+				state, _ := ac.GetPropertyState()
+				return uint32(state)
+			},
+		},
+	}
+	return s.conn.Export(props, p, propertiesInterface)
+}
+
+func (s *Service) exportConnection(p dbus.ObjectPath, conn *Connection) (err error) {
+	methods := map[string]interface{}{
+		"GetSettings": func() (map[string]map[string]dbus.Variant, *dbus.Error) {
+			settings, err := conn.GetSettings()
+			if err != nil {
+				return nil, dbus.MakeFailedError(err)
+			}
+			return encodeSettings(settings), nil
+		},
+		"GetSecrets": func(settingName string) (map[string]map[string]dbus.Variant, *dbus.Error) {
+			secrets, err := conn.GetSecrets(settingName)
+			if err != nil {
+				return nil, dbus.MakeFailedError(err)
+			}
+			return encodeSettings(secrets), nil
+		},
+		"Update": func(settings map[string]map[string]dbus.Variant) *dbus.Error {
+			connection, err := s.decodeSettings(settings)
+			if err != nil {
+				s.Log.Error(fmt.Sprintf("Update - rejected settings: %s", err.Error()))
+				return dbus.NewError("org.freedesktop.NetworkManager.Settings.Connection.InvalidProperty", []interface{}{err.Error()})
+			}
+			err = conn.Update(connection)
+			if err != nil {
+				return dbus.MakeFailedError(err)
+			}
+			return nil
+		},
+		"Delete": func() *dbus.Error {
+			err := conn.Delete()
+			if err != nil {
+				return dbus.MakeFailedError(err)
+			}
+			return nil
+		},
+	}
+	err = s.conn.ExportMethodTable(methods, p, gonetworkmanager.ConnectionInterface)
+	if err != nil {
+		return
+	}
+	props := properties{
+		gonetworkmanager.ConnectionInterface: {
+			"Unsaved":  func() interface{} { return false },
+			"Flags":    func() interface{} { return uint32(0) },
+			"Filename": func() interface{} { return "" },
+		},
+	}
+	return s.conn.Export(props, p, propertiesInterface)
+}
+
+// Get returns the value of a property
+func (p properties) Get(iface string, name string) (dbus.Variant, *dbus.Error) {
+	get, ok := p[iface][name]
+	if !ok {
+		return dbus.Variant{}, dbus.NewError("org.freedesktop.DBus.Error.UnknownProperty", []interface{}{fmt.Sprintf("unknown property %s.%s", iface, name)})
+	}
+	return dbus.MakeVariant(get()), nil
+}
+
+// GetAll returns every property of an interface
+func (p properties) GetAll(iface string) (map[string]dbus.Variant, *dbus.Error) {
+	all := make(map[string]dbus.Variant)
+	for name, get := range p[iface] {
+		all[name] = dbus.MakeVariant(get())
+	}
+	return all, nil
+}
+
+// Set rejects property changes, every property of the fake is read-only
+func (p properties) Set(iface string, name string, value dbus.Variant) *dbus.Error {
+	return dbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly", []interface{}{fmt.Sprintf("property %s.%s is read-only", iface, name)})
+}
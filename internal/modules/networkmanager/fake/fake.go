@@ -0,0 +1,727 @@
+// Package fake provides an in-memory NetworkManager so the network module
+// can be exercised without NetworkManager running on the system bus.
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// DefaultStepInterval is the delay between two scripted states of an activation
+const DefaultStepInterval = 100 * time.Millisecond
+
+// secretKeys holds the settings left out by GetSettings
+var secretKeys = map[string]map[string]bool{
+	"802-11-wireless-security": {"psk": true, "wep-key0": true, "wep-key1": true, "wep-key2": true, "wep-key3": true, "leap-password": true},
+	"802-1x":                   {"password": true, "private-key-password": true, "phase2-private-key-password": true, "pin": true},
+}
+
+// DefaultCapabilities are the capabilities of the devices added by AddWifiDevice, a dual band radio able to host an access point
+var DefaultCapabilities = (models.WifiDeviceCapCipherCCMP | models.WifiDeviceCapRSN | models.WifiDeviceCapAP |
+	models.WifiDeviceCapFreqValid | models.WifiDeviceCapFreq2GHz | models.WifiDeviceCapFreq5GHz).U32()
+
+// subscriberBufferLength is the number of changes a subscriber may fall behind before changes are dropped
+const subscriberBufferLength = 16
+
+// NetworkManager is a scripted, in-memory implementation of interfaces.NetworkManager
+type NetworkManager struct {
+	mu sync.Mutex
+
+	// Devices are returned by GetWifiDevices
+	Devices []*WifiDevice
+	// Saved holds the connection profiles known to the fake
+	Saved []*Connection
+	// Activations holds the state sequence of every upcoming activation, consumed in order.
+	// When empty, activations go straight to activated.
+	Activations [][]gonetworkmanager.NmActiveConnectionState
+	// FailureReasons holds the device state reasons of the upcoming failed activations, consumed in order.
+	// Once the list is exhausted failures have an unknown reason.
+	FailureReasons []gonetworkmanager.NmDeviceStateReason
+	// StepInterval is the delay between two states of an activation, DefaultStepInterval when zero
+	StepInterval time.Duration
+	// Connectivity holds the connectivity reached by every station (non AP) activation, consumed in order.
+	// Once the list is exhausted activations reach full connectivity.
+	Connectivity []gonetworkmanager.NmConnectivity
+	// ActiveConnections records every connection activated through the fake
+	ActiveConnections []*ActiveConnection
+
+	connectivity            gonetworkmanager.NmConnectivity
+	profiles                int
+	connectivitySubscribers []chan gonetworkmanager.NmConnectivity
+}
+
+// WifiDevice is a fake wireless device
+type WifiDevice struct {
+	nm *NetworkManager
+
+	Interface string
+	HWAddress string
+	State     gonetworkmanager.NmDeviceState
+	// Capabilities holds the NMWifiDeviceCapabilities of the device
+	Capabilities uint32
+	// Scans holds the results of GetAccessPoints, consumed in order.
+	// The last scan is repeated once the list is exhausted.
+	Scans [][]*AccessPoint
+	// LastScan is increased by every successful RequestScan
+	LastScan int64
+	// NoScanInAPMode makes RequestScan fail while an access point connection is active on the device
+	NoScanInAPMode bool
+	// IP4Config is the IPv4 configuration reported for the device, nil when it has none
+	IP4Config *IP4Config
+
+	subscribers []chan interfaces.DeviceStateChange
+}
+
+// IP4Config is the IPv4 configuration of a fake device
+type IP4Config struct {
+	// Addresses holds the addresses of the device in CIDR notation
+	Addresses []string
+}
+
+// AccessPoint is a fake access point
+type AccessPoint struct {
+	SSID       string `json:"ssid"`
+	Flags      uint32 `json:"flags"`
+	WPAFlags   uint32 `json:"wpaFlags"`
+	RSNFlags   uint32 `json:"rsnFlags"`
+	Strength   uint8  `json:"strength"`
+	Frequency  uint32 `json:"frequency"`
+	MaxBitrate uint32 `json:"maxBitrate"`
+	HWAddress  string `json:"hwAddress"`
+}
+
+// ActiveConnection is a fake active connection
+type ActiveConnection struct {
+	nm *NetworkManager
+
+	Device      *WifiDevice
+	AccessPoint *AccessPoint
+	Connection  *Connection
+	Deactivated bool
+	// State is the current state of the active connection
+	State gonetworkmanager.NmActiveConnectionState
+	// States holds the upcoming states, one is applied every StepInterval
+	States []gonetworkmanager.NmActiveConnectionState
+
+	subscribers []chan interfaces.ActiveConnectionStateChange
+}
+
+// Connection is a fake connection profile
+type Connection struct {
+	nm *NetworkManager
+
+	Settings gonetworkmanager.ConnectionSettings
+	Deleted  bool
+}
+
+// NewNetworkManager returns an empty fake NetworkManager
+func NewNetworkManager() *NetworkManager {
+	return &NetworkManager{}
+}
+
+// AddWifiDevice registers a managed wireless device with the given scan results
+func (n *NetworkManager) AddWifiDevice(iface string, scans ...[]*AccessPoint) *WifiDevice {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	d := &WifiDevice{
+		nm:           n,
+		Interface:    iface,
+		State:        gonetworkmanager.NmDeviceStateDisconnected,
+		Capabilities: DefaultCapabilities,
+		Scans:        scans,
+	}
+	n.Devices = append(n.Devices, d)
+	return d
+}
+
+// AddConnection registers a saved connection profile
+func (n *NetworkManager) AddConnection(settings gonetworkmanager.ConnectionSettings) *Connection {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.addConnection(settings)
+}
+
+func (n *NetworkManager) addConnection(settings gonetworkmanager.ConnectionSettings) *Connection {
+	if settings["connection"] == nil {
+		settings["connection"] = make(map[string]interface{})
+	}
+	n.profiles++
+	if _, ok := settings["connection"]["uuid"]; !ok {
+		settings["connection"]["uuid"] = fmt.Sprintf("00000000-0000-4000-8000-%012d", n.profiles)
+	}
+	conn := &Connection{
+		nm:       n,
+		Settings: settings,
+	}
+	n.Saved = append(n.Saved, conn)
+	return conn
+}
+
+// GetWifiDevices returns the fake wireless devices
+func (n *NetworkManager) GetWifiDevices() (devices []interfaces.WifiDevice, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, d := range n.Devices {
+		devices = append(devices, d)
+	}
+	return
+}
+
+// GetIP4Networks returns the subnets of the IPv4 configurations of the devices
+func (n *NetworkManager) GetIP4Networks() (networks []*net.IPNet, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, d := range n.Devices {
+		if d.IP4Config == nil {
+			continue
+		}
+		for _, address := range d.IP4Config.Addresses {
+			var network *net.IPNet
+			_, network, err = net.ParseCIDR(address)
+			if err != nil {
+				return
+			}
+			networks = append(networks, network)
+		}
+	}
+	return
+}
+
+// ListConnections returns the saved connection profiles
+func (n *NetworkManager) ListConnections() (conns []interfaces.Connection, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, conn := range n.Saved {
+		conns = append(conns, conn)
+	}
+	return
+}
+
+// AddAndActivateConnection saves the connection and activates it using the next scripted activation
+func (n *NetworkManager) AddAndActivateConnection(connection map[string]map[string]interface{}, d interfaces.WifiDevice) (ac interfaces.ActiveConnection, err error) {
+	return n.activate(connection, d, nil)
+}
+
+// AddAndActivateWirelessConnection saves the connection and activates it using the next scripted activation.
+// The SSID missing from the settings is taken from the access point, as NetworkManager does.
+func (n *NetworkManager) AddAndActivateWirelessConnection(connection map[string]map[string]interface{}, d interfaces.WifiDevice, ap interfaces.AccessPoint) (ac interfaces.ActiveConnection, err error) {
+	fakeAP, ok := ap.(*AccessPoint)
+	if !ok {
+		err = errors.New("unknown access point")
+		return
+	}
+	if connection["802-11-wireless"] == nil {
+		connection["802-11-wireless"] = make(map[string]interface{})
+	}
+	if _, ok := connection["802-11-wireless"]["ssid"]; !ok {
+		connection["802-11-wireless"]["ssid"] = []byte(fakeAP.SSID)
+	}
+	return n.activate(connection, d, fakeAP)
+}
+
+func (n *NetworkManager) activate(connection map[string]map[string]interface{}, d interfaces.WifiDevice, ap *AccessPoint) (ac interfaces.ActiveConnection, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	device, ok := d.(*WifiDevice)
+	if !ok || device.nm != n {
+		err = errors.New("unknown device")
+		return
+	}
+	states := []gonetworkmanager.NmActiveConnectionState{gonetworkmanager.NmActiveConnectionStateActivated}
+	if len(n.Activations) > 0 {
+		states = n.Activations[0]
+		n.Activations = n.Activations[1:]
+	}
+	a := &ActiveConnection{
+		nm:          n,
+		Device:      device,
+		AccessPoint: ap,
+		Connection:  n.addConnection(connection),
+		States:      states,
+	}
+	n.ActiveConnections = append(n.ActiveConnections, a)
+	a.step()
+	go a.play()
+	ac = a
+	return
+}
+
+// DeactivateConnection moves the active connection to the deactivated state
+func (n *NetworkManager) DeactivateConnection(ac interfaces.ActiveConnection) (err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	a, ok := ac.(*ActiveConnection)
+	if !ok || a.nm != n {
+		err = errors.New("unknown active connection")
+		return
+	}
+	if a.Deactivated {
+		return
+	}
+	a.Deactivated = true
+	a.States = nil
+	a.setState(gonetworkmanager.NmActiveConnectionStateDeactivating, gonetworkmanager.NmActiveConnectionStateReasonUserDisconnected)
+	a.setState(gonetworkmanager.NmActiveConnectionStateDeactivated, gonetworkmanager.NmActiveConnectionStateReasonUserDisconnected)
+	return
+}
+
+// GetPropertyConnectivity returns the current connectivity
+func (n *NetworkManager) GetPropertyConnectivity() (connectivity gonetworkmanager.NmConnectivity, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.connectivity, nil
+}
+
+// SubscribeConnectivity sends every connectivity change until ctx is done
+func (n *NetworkManager) SubscribeConnectivity(ctx context.Context) (<-chan gonetworkmanager.NmConnectivity, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ch := make(chan gonetworkmanager.NmConnectivity, subscriberBufferLength)
+	n.connectivitySubscribers = append(n.connectivitySubscribers, ch)
+	go func() {
+		<-ctx.Done()
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		for i, sub := range n.connectivitySubscribers {
+			if sub == ch {
+				n.connectivitySubscribers = append(n.connectivitySubscribers[:i], n.connectivitySubscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// nextConnectivity applies the connectivity of a station activation, the caller holds the lock
+func (n *NetworkManager) nextConnectivity() {
+	connectivity := gonetworkmanager.NmConnectivityFull
+	if len(n.Connectivity) > 0 {
+		connectivity = n.Connectivity[0]
+		n.Connectivity = n.Connectivity[1:]
+	}
+	if connectivity == n.connectivity {
+		return
+	}
+	n.connectivity = connectivity
+	for _, ch := range n.connectivitySubscribers {
+		select {
+		case ch <- connectivity:
+		default:
+		}
+	}
+}
+
+func (n *NetworkManager) stepInterval() time.Duration {
+	if n.StepInterval > 0 {
+		return n.StepInterval
+	}
+	return DefaultStepInterval
+}
+
+// GetPropertyInterface returns the interface name of the device
+func (d *WifiDevice) GetPropertyInterface() (string, error) {
+	return d.Interface, nil
+}
+
+// GetPropertyState returns the state of the device
+func (d *WifiDevice) GetPropertyState() (gonetworkmanager.NmDeviceState, error) {
+	d.nm.mu.Lock()
+	defer d.nm.mu.Unlock()
+	return d.State, nil
+}
+
+// GetPropertyHwAddress returns the MAC address of the device
+func (d *WifiDevice) GetPropertyHwAddress() (string, error) {
+	return d.HWAddress, nil
+}
+
+// GetPropertyWirelessCapabilities returns the capabilities of the device
+func (d *WifiDevice) GetPropertyWirelessCapabilities() (uint32, error) {
+	return d.Capabilities, nil
+}
+
+// SubscribeState sends every state change of the device until ctx is done
+func (d *WifiDevice) SubscribeState(ctx context.Context) (<-chan interfaces.DeviceStateChange, error) {
+	d.nm.mu.Lock()
+	defer d.nm.mu.Unlock()
+	ch := make(chan interfaces.DeviceStateChange, subscriberBufferLength)
+	d.subscribers = append(d.subscribers, ch)
+	go func() {
+		<-ctx.Done()
+		d.nm.mu.Lock()
+		defer d.nm.mu.Unlock()
+		for i, sub := range d.subscribers {
+			if sub == ch {
+				d.subscribers = append(d.subscribers[:i], d.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// setState changes the state of the device and notifies the subscribers, the caller holds the lock
+func (d *WifiDevice) setState(state gonetworkmanager.NmDeviceState, reason gonetworkmanager.NmDeviceStateReason) {
+	if state == d.State {
+		return
+	}
+	change := interfaces.DeviceStateChange{
+		State:    state,
+		OldState: d.State,
+		Reason:   reason,
+	}
+	d.State = state
+	for _, ch := range d.subscribers {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
+
+// GetAccessPoints returns the next scripted scan result
+func (d *WifiDevice) GetAccessPoints() (aps []interfaces.AccessPoint, err error) {
+	d.nm.mu.Lock()
+	defer d.nm.mu.Unlock()
+	if len(d.Scans) == 0 {
+		return
+	}
+	for _, ap := range d.Scans[0] {
+		aps = append(aps, ap)
+	}
+	if len(d.Scans) > 1 {
+		d.Scans = d.Scans[1:]
+	}
+	return
+}
+
+// RequestScan completes a scan immediately, unless the device refuses to scan in AP mode
+func (d *WifiDevice) RequestScan() error {
+	d.nm.mu.Lock()
+	defer d.nm.mu.Unlock()
+	if d.NoScanInAPMode {
+		for _, ac := range d.nm.ActiveConnections {
+			if mode, _ := ac.Connection.Settings["802-11-wireless"]["mode"].(string); ac.Device == d && !ac.Deactivated && mode == "ap" {
+				return errors.New("scanning not allowed while in AP mode")
+			}
+		}
+	}
+	d.LastScan++
+	return nil
+}
+
+// GetPropertyLastScan returns the counter of completed scans
+func (d *WifiDevice) GetPropertyLastScan() (int64, error) {
+	d.nm.mu.Lock()
+	defer d.nm.mu.Unlock()
+	return d.LastScan, nil
+}
+
+// GetPropertyAvailableConnections returns the saved wireless connection profiles
+func (d *WifiDevice) GetPropertyAvailableConnections() (conns []interfaces.Connection, err error) {
+	d.nm.mu.Lock()
+	defer d.nm.mu.Unlock()
+	for _, conn := range d.nm.Saved {
+		if _, ok := conn.Settings["802-11-wireless"]; ok {
+			conns = append(conns, conn)
+		}
+	}
+	return
+}
+
+// OpenAccessPoint returns an access point without security
+func OpenAccessPoint(ssid string, strength uint8) *AccessPoint {
+	return &AccessPoint{
+		SSID:     ssid,
+		Strength: strength,
+	}
+}
+
+// WPA2AccessPoint returns a WPA2-PSK access point
+func WPA2AccessPoint(ssid string, strength uint8) *AccessPoint {
+	return &AccessPoint{
+		SSID:     ssid,
+		Flags:    models.ApFlagsPrivacy.U32(),
+		RSNFlags: (models.ApSecPairCCMP | models.ApSecGroupCCMP | models.ApSecKeyMGMTPSK).U32(),
+		Strength: strength,
+	}
+}
+
+// EnterpriseAccessPoint returns a WPA2-Enterprise access point
+func EnterpriseAccessPoint(ssid string, strength uint8) *AccessPoint {
+	return &AccessPoint{
+		SSID:     ssid,
+		Flags:    models.ApFlagsPrivacy.U32(),
+		RSNFlags: (models.ApSecPairCCMP | models.ApSecGroupCCMP | models.ApSecKeyMgmt8021X).U32(),
+		Strength: strength,
+	}
+}
+
+// WPA3AccessPoint returns a WPA3-SAE only access point
+func WPA3AccessPoint(ssid string, strength uint8) *AccessPoint {
+	return &AccessPoint{
+		SSID:     ssid,
+		Flags:    models.ApFlagsPrivacy.U32(),
+		RSNFlags: (models.ApSecPairCCMP | models.ApSecGroupCCMP | models.ApSecKeyMgmtSAE).U32(),
+		Strength: strength,
+	}
+}
+
+// TransitionAccessPoint returns a WPA2/WPA3 transition mode access point
+func TransitionAccessPoint(ssid string, strength uint8) *AccessPoint {
+	return &AccessPoint{
+		SSID:     ssid,
+		Flags:    models.ApFlagsPrivacy.U32(),
+		RSNFlags: (models.ApSecPairCCMP | models.ApSecGroupCCMP | models.ApSecKeyMGMTPSK | models.ApSecKeyMgmtSAE).U32(),
+		Strength: strength,
+	}
+}
+
+// OWEAccessPoint returns an OWE (enhanced open) access point
+func OWEAccessPoint(ssid string, strength uint8) *AccessPoint {
+	return &AccessPoint{
+		SSID:     ssid,
+		Flags:    models.ApFlagsPrivacy.U32(),
+		RSNFlags: (models.ApSecPairCCMP | models.ApSecGroupCCMP | models.ApSecKeyMgmtOWE).U32(),
+		Strength: strength,
+	}
+}
+
+// OWETransitionAccessPoint returns the open access point of an OWE transition mode pair, pointing to a hidden OWE access point
+func OWETransitionAccessPoint(ssid string, strength uint8) *AccessPoint {
+	return &AccessPoint{
+		SSID:     ssid,
+		RSNFlags: models.ApSecKeyMgmtOWETM.U32(),
+		Strength: strength,
+	}
+}
+
+// SuiteB192AccessPoint returns a WPA3-Enterprise 192-bit mode access point
+func SuiteB192AccessPoint(ssid string, strength uint8) *AccessPoint {
+	return &AccessPoint{
+		SSID:     ssid,
+		Flags:    models.ApFlagsPrivacy.U32(),
+		RSNFlags: models.ApSecKeyMgmtEAPSuiteB192.U32(),
+		Strength: strength,
+	}
+}
+
+// GetPropertySSID returns the SSID of the access point
+func (a *AccessPoint) GetPropertySSID() (string, error) {
+	return a.SSID, nil
+}
+
+// GetPropertyFlags returns the capability flags of the access point
+func (a *AccessPoint) GetPropertyFlags() (uint32, error) {
+	return a.Flags, nil
+}
+
+// GetPropertyWPAFlags returns the WPA flags of the access point
+func (a *AccessPoint) GetPropertyWPAFlags() (uint32, error) {
+	return a.WPAFlags, nil
+}
+
+// GetPropertyRSNFlags returns the RSN flags of the access point
+func (a *AccessPoint) GetPropertyRSNFlags() (uint32, error) {
+	return a.RSNFlags, nil
+}
+
+// GetPropertyStrength returns the signal strength of the access point
+func (a *AccessPoint) GetPropertyStrength() (uint8, error) {
+	return a.Strength, nil
+}
+
+// GetPropertyFrequency returns the frequency of the access point in MHz
+func (a *AccessPoint) GetPropertyFrequency() (uint32, error) {
+	return a.Frequency, nil
+}
+
+// GetPropertyMaxBitrate returns the maximum bitrate of the access point in kbit/s
+func (a *AccessPoint) GetPropertyMaxBitrate() (uint32, error) {
+	return a.MaxBitrate, nil
+}
+
+// GetPropertyHWAddress returns the BSSID of the access point
+func (a *AccessPoint) GetPropertyHWAddress() (string, error) {
+	return a.HWAddress, nil
+}
+
+// GetPropertyState returns the current state of the active connection
+func (a *ActiveConnection) GetPropertyState() (state gonetworkmanager.NmActiveConnectionState, err error) {
+	a.nm.mu.Lock()
+	defer a.nm.mu.Unlock()
+	return a.State, nil
+}
+
+// SubscribeState sends every state change of the active connection until ctx is done
+func (a *ActiveConnection) SubscribeState(ctx context.Context) (<-chan interfaces.ActiveConnectionStateChange, error) {
+	a.nm.mu.Lock()
+	defer a.nm.mu.Unlock()
+	ch := make(chan interfaces.ActiveConnectionStateChange, subscriberBufferLength)
+	a.subscribers = append(a.subscribers, ch)
+	go func() {
+		<-ctx.Done()
+		a.nm.mu.Lock()
+		defer a.nm.mu.Unlock()
+		for i, sub := range a.subscribers {
+			if sub == ch {
+				a.subscribers = append(a.subscribers[:i], a.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// play applies the scripted states one every StepInterval
+func (a *ActiveConnection) play() {
+	for {
+		time.Sleep(a.nm.stepInterval())
+		a.nm.mu.Lock()
+		if a.Deactivated || len(a.States) == 0 {
+			a.nm.mu.Unlock()
+			return
+		}
+		a.step()
+		a.nm.mu.Unlock()
+	}
+}
+
+// step applies the next scripted state, the caller holds the lock
+func (a *ActiveConnection) step() {
+	if len(a.States) == 0 {
+		return
+	}
+	state := a.States[0]
+	a.States = a.States[1:]
+	if state == gonetworkmanager.NmActiveConnectionStateDeactivated {
+		// a scripted deactivation is a failed activation, NetworkManager reports the cause on the device
+		a.Deactivated = true
+		a.States = nil
+		reason := gonetworkmanager.NmDeviceStateReason(gonetworkmanager.NmDeviceStateReasonUnknown)
+		if len(a.nm.FailureReasons) > 0 {
+			reason = a.nm.FailureReasons[0]
+			a.nm.FailureReasons = a.nm.FailureReasons[1:]
+		}
+		a.Device.setState(gonetworkmanager.NmDeviceStateFailed, reason)
+		a.setState(state, gonetworkmanager.NmActiveConnectionStateReasonDeviceDisconnected)
+		return
+	}
+	a.setState(state, gonetworkmanager.NmActiveConnectionStateReasonNone)
+}
+
+// setState changes the state of the active connection and of its device, the caller holds the lock
+func (a *ActiveConnection) setState(state gonetworkmanager.NmActiveConnectionState, reason gonetworkmanager.NmActiveConnectionStateReason) {
+	if state == a.State {
+		return
+	}
+	a.State = state
+	change := interfaces.ActiveConnectionStateChange{
+		State:  state,
+		Reason: reason,
+	}
+	for _, ch := range a.subscribers {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+	switch state {
+	case gonetworkmanager.NmActiveConnectionStateActivating:
+		a.Device.setState(gonetworkmanager.NmDeviceStateConfig, gonetworkmanager.NmDeviceStateReasonNone)
+	case gonetworkmanager.NmActiveConnectionStateActivated:
+		a.Device.setState(gonetworkmanager.NmDeviceStateActivated, gonetworkmanager.NmDeviceStateReasonNone)
+		a.Connection.Settings["connection"]["timestamp"] = uint64(time.Now().Unix())
+		if mode, _ := a.Connection.Settings["802-11-wireless"]["mode"].(string); mode != "ap" {
+			a.nm.nextConnectivity()
+		}
+	case gonetworkmanager.NmActiveConnectionStateDeactivating:
+		a.Device.setState(gonetworkmanager.NmDeviceStateDeactivating, gonetworkmanager.NmDeviceStateReasonUserRequested)
+	case gonetworkmanager.NmActiveConnectionStateDeactivated:
+		a.Device.setState(gonetworkmanager.NmDeviceStateDisconnected, gonetworkmanager.NmDeviceStateReasonNone)
+	}
+}
+
+// GetPropertyConnection returns the connection profile of the active connection
+func (a *ActiveConnection) GetPropertyConnection() (interfaces.Connection, error) {
+	return a.Connection, nil
+}
+
+// GetSettings returns a copy of the settings of the connection profile without its secrets
+func (c *Connection) GetSettings() (gonetworkmanager.ConnectionSettings, error) {
+	c.nm.mu.Lock()
+	defer c.nm.mu.Unlock()
+	if c.Deleted {
+		return nil, errors.New("connection deleted")
+	}
+	settings := make(gonetworkmanager.ConnectionSettings)
+	for name, setting := range c.Settings {
+		settings[name] = make(map[string]interface{})
+		for key, value := range setting {
+			if !secretKeys[name][key] {
+				settings[name][key] = value
+			}
+		}
+	}
+	return settings, nil
+}
+
+// GetSecrets returns the secrets of one setting of the connection profile
+func (c *Connection) GetSecrets(settingName string) (gonetworkmanager.ConnectionSettings, error) {
+	c.nm.mu.Lock()
+	defer c.nm.mu.Unlock()
+	if c.Deleted {
+		return nil, errors.New("connection deleted")
+	}
+	secrets := map[string]interface{}{}
+	for key, value := range c.Settings[settingName] {
+		if secretKeys[settingName][key] {
+			secrets[key] = value
+		}
+	}
+	return gonetworkmanager.ConnectionSettings{settingName: secrets}, nil
+}
+
+// Update replaces the settings of the connection profile, secrets missing from the settings are dropped as
+// NetworkManager does
+func (c *Connection) Update(settings gonetworkmanager.ConnectionSettings) error {
+	c.nm.mu.Lock()
+	defer c.nm.mu.Unlock()
+	if c.Deleted {
+		return errors.New("connection deleted")
+	}
+	uuid, _ := settings["connection"]["uuid"].(string)
+	if current, _ := c.Settings["connection"]["uuid"].(string); uuid != current {
+		return errors.New("connection uuid can't be changed")
+	}
+	c.Settings = settings
+	return nil
+}
+
+// Delete removes the connection profile
+func (c *Connection) Delete() error {
+	c.nm.mu.Lock()
+	defer c.nm.mu.Unlock()
+	if c.Deleted {
+		return errors.New("connection deleted")
+	}
+	c.Deleted = true
+	for i, conn := range c.nm.Saved {
+		if conn == c {
+			c.nm.Saved = append(c.nm.Saved[:i], c.nm.Saved[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
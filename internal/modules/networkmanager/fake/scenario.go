@@ -0,0 +1,174 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Wifx/gonetworkmanager"
+)
+
+// Scenario describes the initial state and the scripted behaviour of a fake NetworkManager
+type Scenario struct {
+	Devices []struct {
+		Interface      string           `json:"interface"`
+		HWAddress      string           `json:"hwAddress"`
+		Capabilities   *uint32          `json:"capabilities"`
+		Unmanaged      bool             `json:"unmanaged"`
+		Scans          [][]*AccessPoint `json:"scans"`
+		NoScanInAPMode bool             `json:"noScanInAPMode"`
+		IP4Addresses   []string         `json:"ip4Addresses"`
+	} `json:"devices"`
+	Saved          []gonetworkmanager.ConnectionSettings `json:"saved"`
+	Activations    [][]string                            `json:"activations"`
+	StepInterval   string                                `json:"stepInterval"`
+	FailureReasons []string                              `json:"failureReasons"`
+	Connectivity   []string                              `json:"connectivity"`
+}
+
+var activeConnectionStates = map[string]gonetworkmanager.NmActiveConnectionState{
+	"unknown":      gonetworkmanager.NmActiveConnectionStateUnknown,
+	"activating":   gonetworkmanager.NmActiveConnectionStateActivating,
+	"activated":    gonetworkmanager.NmActiveConnectionStateActivated,
+	"deactivating": gonetworkmanager.NmActiveConnectionStateDeactivating,
+	"deactivated":  gonetworkmanager.NmActiveConnectionStateDeactivated,
+}
+
+var deviceStateReasons = map[string]gonetworkmanager.NmDeviceStateReason{
+	"unknown":               gonetworkmanager.NmDeviceStateReasonUnknown,
+	"ip-config-unavailable": gonetworkmanager.NmDeviceStateReasonIpConfigUnavailable,
+	"no-secrets":            gonetworkmanager.NmDeviceStateReasonNoSecrets,
+	"supplicant-disconnect": gonetworkmanager.NmDeviceStateReasonSupplicantDisconnect,
+	"supplicant-failed":     gonetworkmanager.NmDeviceStateReasonSupplicantFailed,
+	"supplicant-timeout":    gonetworkmanager.NmDeviceStateReasonSupplicantTimeout,
+	"dhcp-failed":           gonetworkmanager.NmDeviceStateReasonDhcpFailed,
+	"ssid-not-found":        gonetworkmanager.NmDeviceStateReasonSsidNotFound,
+}
+
+var connectivityStates = map[string]gonetworkmanager.NmConnectivity{
+	"unknown": gonetworkmanager.NmConnectivityUnknown,
+	"none":    gonetworkmanager.NmConnectivityNone,
+	"portal":  gonetworkmanager.NmConnectivityPortal,
+	"limited": gonetworkmanager.NmConnectivityLimited,
+	"full":    gonetworkmanager.NmConnectivityFull,
+}
+
+// LoadScenario reads a JSON scenario file and returns a fake NetworkManager initialised from it
+func LoadScenario(path string) (nm *NetworkManager, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		err = fmt.Errorf("found error on reading scenario [%s]", err.Error())
+		return
+	}
+	var sc Scenario
+	err = json.Unmarshal(data, &sc)
+	if err != nil {
+		err = fmt.Errorf("found error on parsing scenario [%s]", err.Error())
+		return
+	}
+	return NewNetworkManagerFromScenario(sc)
+}
+
+// NewNetworkManagerFromScenario returns a fake NetworkManager initialised from the scenario
+func NewNetworkManagerFromScenario(sc Scenario) (nm *NetworkManager, err error) {
+	nm = NewNetworkManager()
+	for _, d := range sc.Devices {
+		device := nm.AddWifiDevice(d.Interface, d.Scans...)
+		device.HWAddress = d.HWAddress
+		device.NoScanInAPMode = d.NoScanInAPMode
+		if d.Capabilities != nil {
+			device.Capabilities = *d.Capabilities
+		}
+		if len(d.IP4Addresses) > 0 {
+			device.IP4Config = &IP4Config{Addresses: d.IP4Addresses}
+		}
+		if d.Unmanaged {
+			device.State = gonetworkmanager.NmDeviceStateUnmanaged
+		}
+	}
+	for _, settings := range sc.Saved {
+		fromJSON(settings)
+		nm.AddConnection(settings)
+	}
+	for _, activation := range sc.Activations {
+		var states []gonetworkmanager.NmActiveConnectionState
+		for _, name := range activation {
+			state, ok := activeConnectionStates[name]
+			if !ok {
+				err = fmt.Errorf("unknown active connection state %q", name)
+				return
+			}
+			states = append(states, state)
+		}
+		nm.Activations = append(nm.Activations, states)
+	}
+	if sc.StepInterval != "" {
+		nm.StepInterval, err = time.ParseDuration(sc.StepInterval)
+		if err != nil {
+			err = fmt.Errorf("found error on parsing stepInterval [%s]", err.Error())
+			return
+		}
+	}
+	for _, name := range sc.FailureReasons {
+		reason, ok := deviceStateReasons[name]
+		if !ok {
+			err = fmt.Errorf("unknown device state reason %q", name)
+			return
+		}
+		nm.FailureReasons = append(nm.FailureReasons, reason)
+	}
+	for _, name := range sc.Connectivity {
+		connectivity, ok := connectivityStates[name]
+		if !ok {
+			err = fmt.Errorf("unknown connectivity state %q", name)
+			return
+		}
+		nm.Connectivity = append(nm.Connectivity, connectivity)
+	}
+	return
+}
+
+// fromJSON converts the values decoded from JSON to the types NetworkManager uses for the settings.
+// JSON has no byte strings, byte arrays like the SSID are given as plain text.
+func fromJSON(settings gonetworkmanager.ConnectionSettings) {
+	for name, setting := range settings {
+		for key, value := range setting {
+			switch v := value.(type) {
+			case string:
+				if settingSignatures[name][key] == "ay" {
+					setting[key] = []byte(v)
+				}
+			case float64:
+				switch settingSignatures[name][key] {
+				case "i":
+					setting[key] = int32(v)
+				case "u":
+					setting[key] = uint32(v)
+				case "t":
+					setting[key] = uint64(v)
+				}
+			case []interface{}:
+				if settingSignatures[name][key] == "aa{sv}" {
+					setting[key] = addressDataFromJSON(v)
+				}
+			}
+		}
+	}
+}
+
+// addressDataFromJSON converts an address-data list, the prefix is the only number of its entries
+func addressDataFromJSON(list []interface{}) []map[string]interface{} {
+	addressData := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if prefix, ok := entry["prefix"].(float64); ok {
+			entry["prefix"] = uint32(prefix)
+		}
+		addressData = append(addressData, entry)
+	}
+	return addressData
+}
@@ -0,0 +1,205 @@
+package networkmanager
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
+)
+
+// NetworkManager implements interfaces.NetworkManager on top of the
+// NetworkManager D-Bus API
+type NetworkManager struct {
+	NM gonetworkmanager.NetworkManager
+}
+
+// wifiDevice adapts gonetworkmanager.DeviceWireless to interfaces.WifiDevice
+type wifiDevice struct {
+	gonetworkmanager.DeviceWireless
+}
+
+// activeConnection adapts gonetworkmanager.ActiveConnection to interfaces.ActiveConnection
+type activeConnection struct {
+	gonetworkmanager.ActiveConnection
+}
+
+// NewNetworkManager returns access to this module
+func NewNetworkManager() (*NetworkManager, error) {
+	nm, err := gonetworkmanager.NewNetworkManager()
+	if err != nil {
+		err = fmt.Errorf("found error on NewNetworkManager [%s]", err.Error())
+		return nil, err
+	}
+	return &NetworkManager{
+		NM: nm,
+	}, nil
+}
+
+// GetWifiDevices returns every wireless device known to NetworkManager
+func (n *NetworkManager) GetWifiDevices() (devices []interfaces.WifiDevice, err error) {
+	all, err := n.NM.GetAllDevices()
+	if err != nil {
+		err = fmt.Errorf("found error on GetAllDevices [%s]", err.Error())
+		return
+	}
+	for _, device := range all {
+		var dType gonetworkmanager.NmDeviceType
+		dType, err = device.GetPropertyDeviceType()
+		if err != nil {
+			err = fmt.Errorf("found error on GetPropertyDeviceType [%s]", err.Error())
+			return
+		}
+		if dType != gonetworkmanager.NmDeviceTypeWifi {
+			continue
+		}
+		var wd gonetworkmanager.DeviceWireless
+		wd, err = gonetworkmanager.NewDeviceWireless(device.GetPath())
+		if err != nil {
+			err = fmt.Errorf("found error on NewDeviceWireless [%s]", err.Error())
+			return
+		}
+		devices = append(devices, &wifiDevice{wd})
+	}
+	return
+}
+
+// GetIP4Networks returns the IPv4 subnets configured on every device, whatever its type
+func (n *NetworkManager) GetIP4Networks() (networks []*net.IPNet, err error) {
+	all, err := n.NM.GetAllDevices()
+	if err != nil {
+		err = fmt.Errorf("found error on GetAllDevices [%s]", err.Error())
+		return
+	}
+	for _, device := range all {
+		var ip4Config gonetworkmanager.IP4Config
+		ip4Config, err = device.GetPropertyIP4Config()
+		if err != nil {
+			err = fmt.Errorf("found error on GetPropertyIP4Config [%s]", err.Error())
+			return
+		}
+		if ip4Config == nil {
+			continue
+		}
+		var addresses []gonetworkmanager.IP4AddressData
+		addresses, err = ip4Config.GetPropertyAddressData()
+		if err != nil {
+			err = fmt.Errorf("found error on GetPropertyAddressData [%s]", err.Error())
+			return
+		}
+		for _, address := range addresses {
+			_, network, parseErr := net.ParseCIDR(fmt.Sprintf("%s/%d", address.Address, address.Prefix))
+			if parseErr == nil {
+				networks = append(networks, network)
+			}
+		}
+	}
+	return
+}
+
+// ListConnections returns every connection profile stored by NetworkManager
+func (n *NetworkManager) ListConnections() (conns []interfaces.Connection, err error) {
+	settings, err := gonetworkmanager.NewSettings()
+	if err != nil {
+		err = fmt.Errorf("found error on NewSettings [%s]", err.Error())
+		return
+	}
+	nmConns, err := settings.ListConnections()
+	if err != nil {
+		return
+	}
+	conns = make([]interfaces.Connection, len(nmConns))
+	for i, conn := range nmConns {
+		conns[i] = conn
+	}
+	return
+}
+
+// AddAndActivateConnection adds a new connection and activates it on the device
+func (n *NetworkManager) AddAndActivateConnection(connection map[string]map[string]interface{}, d interfaces.WifiDevice) (ac interfaces.ActiveConnection, err error) {
+	wd, err := toDevice(d)
+	if err != nil {
+		return
+	}
+	var nmAC gonetworkmanager.ActiveConnection
+	nmAC, err = n.NM.AddAndActivateConnection(connection, wd)
+	if err != nil {
+		return
+	}
+	ac = &activeConnection{nmAC}
+	return
+}
+
+// AddAndActivateWirelessConnection adds a new connection and activates it on the device using the access point
+func (n *NetworkManager) AddAndActivateWirelessConnection(connection map[string]map[string]interface{}, d interfaces.WifiDevice, ap interfaces.AccessPoint) (ac interfaces.ActiveConnection, err error) {
+	wd, err := toDevice(d)
+	if err != nil {
+		return
+	}
+	nmAP, ok := ap.(gonetworkmanager.AccessPoint)
+	if !ok {
+		err = fmt.Errorf("unsupported access point type %T", ap)
+		return
+	}
+	var nmAC gonetworkmanager.ActiveConnection
+	nmAC, err = n.NM.AddAndActivateWirelessConnection(connection, wd, nmAP)
+	if err != nil {
+		return
+	}
+	ac = &activeConnection{nmAC}
+	return
+}
+
+// DeactivateConnection deactivates the active connection
+func (n *NetworkManager) DeactivateConnection(ac interfaces.ActiveConnection) (err error) {
+	nmAC, ok := ac.(*activeConnection)
+	if !ok {
+		err = fmt.Errorf("unsupported active connection type %T", ac)
+		return
+	}
+	return n.NM.DeactivateConnection(nmAC.ActiveConnection)
+}
+
+// GetPropertyConnectivity returns the network connectivity state
+func (n *NetworkManager) GetPropertyConnectivity() (gonetworkmanager.NmConnectivity, error) {
+	return n.NM.GetPropertyConnectivity()
+}
+
+// GetAccessPoints returns the access points visible to the device
+func (d *wifiDevice) GetAccessPoints() (aps []interfaces.AccessPoint, err error) {
+	nmAPs, err := d.DeviceWireless.GetAccessPoints()
+	if err != nil {
+		return
+	}
+	aps = make([]interfaces.AccessPoint, len(nmAPs))
+	for i, ap := range nmAPs {
+		aps[i] = ap
+	}
+	return
+}
+
+// GetPropertyAvailableConnections returns the connections which could be activated on the device
+func (d *wifiDevice) GetPropertyAvailableConnections() (conns []interfaces.Connection, err error) {
+	nmConns, err := d.DeviceWireless.GetPropertyAvailableConnections()
+	if err != nil {
+		return
+	}
+	conns = make([]interfaces.Connection, len(nmConns))
+	for i, conn := range nmConns {
+		conns[i] = conn
+	}
+	return
+}
+
+// GetPropertyConnection returns the connection profile of the active connection
+func (a *activeConnection) GetPropertyConnection() (interfaces.Connection, error) {
+	return a.ActiveConnection.GetPropertyConnection()
+}
+
+func toDevice(d interfaces.WifiDevice) (gonetworkmanager.DeviceWireless, error) {
+	wd, ok := d.(*wifiDevice)
+	if !ok {
+		return nil, fmt.Errorf("unsupported device type %T", d)
+	}
+	return wd.DeviceWireless, nil
+}
@@ -0,0 +1,152 @@
+package networkmanager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Wifx/gonetworkmanager"
+	"github.com/godbus/dbus/v5"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/interfaces"
+)
+
+const (
+	propertiesInterface       = "org.freedesktop.DBus.Properties"
+	propertiesChangedSignal   = "PropertiesChanged"
+	deviceStateChangedSignal  = "StateChanged"
+	connectivityPropertyName  = "Connectivity"
+	signalChannelBufferLength = 10
+)
+
+// SubscribeConnectivity sends every change of the network connectivity state until ctx is done
+func (n *NetworkManager) SubscribeConnectivity(ctx context.Context) (<-chan gonetworkmanager.NmConnectivity, error) {
+	signals, err := subscribe(ctx, dbus.ObjectPath(gonetworkmanager.NetworkManagerObjectPath), propertiesInterface, propertiesChangedSignal)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(chan gonetworkmanager.NmConnectivity, signalChannelBufferLength)
+	go func() {
+		defer close(changes)
+		for signal := range signals {
+			var iface string
+			var changed map[string]dbus.Variant
+			var invalidated []string
+			if dbus.Store(signal.Body, &iface, &changed, &invalidated) != nil || iface != gonetworkmanager.NetworkManagerInterface {
+				continue
+			}
+			value, ok := changed[connectivityPropertyName]
+			if !ok {
+				continue
+			}
+			connectivity, ok := value.Value().(uint32)
+			if !ok {
+				continue
+			}
+			select {
+			case changes <- gonetworkmanager.NmConnectivity(connectivity):
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// SubscribeState sends every state change of the device until ctx is done
+func (d *wifiDevice) SubscribeState(ctx context.Context) (<-chan interfaces.DeviceStateChange, error) {
+	signals, err := subscribe(ctx, d.GetPath(), gonetworkmanager.DeviceInterface, deviceStateChangedSignal)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(chan interfaces.DeviceStateChange, signalChannelBufferLength)
+	go func() {
+		defer close(changes)
+		for signal := range signals {
+			var state, oldState, reason uint32
+			if dbus.Store(signal.Body, &state, &oldState, &reason) != nil {
+				continue
+			}
+			change := interfaces.DeviceStateChange{
+				State:    gonetworkmanager.NmDeviceState(state),
+				OldState: gonetworkmanager.NmDeviceState(oldState),
+				Reason:   gonetworkmanager.NmDeviceStateReason(reason),
+			}
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// SubscribeState sends every state change of the active connection until ctx is done
+func (a *activeConnection) SubscribeState(ctx context.Context) (<-chan interfaces.ActiveConnectionStateChange, error) {
+	signals, err := subscribe(ctx, a.GetPath(), gonetworkmanager.ActiveConnectionInterface, gonetworkmanager.ActiveConnectionSignalStateChanged)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(chan interfaces.ActiveConnectionStateChange, signalChannelBufferLength)
+	go func() {
+		defer close(changes)
+		for signal := range signals {
+			var state, reason uint32
+			if dbus.Store(signal.Body, &state, &reason) != nil {
+				continue
+			}
+			change := interfaces.ActiveConnectionStateChange{
+				State:  gonetworkmanager.NmActiveConnectionState(state),
+				Reason: gonetworkmanager.NmActiveConnectionStateReason(reason),
+			}
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return changes, nil
+}
+
+// subscribe forwards the signals emitted by the object on the system bus until ctx is done.
+// The match rule is registered before returning so no signal sent afterwards is missed.
+func subscribe(ctx context.Context, path dbus.ObjectPath, iface string, member string) (<-chan *dbus.Signal, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("found error on SystemBus [%s]", err.Error())
+	}
+	options := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(path),
+		dbus.WithMatchInterface(iface),
+		dbus.WithMatchMember(member),
+	}
+	err = conn.AddMatchSignal(options...)
+	if err != nil {
+		return nil, fmt.Errorf("found error on AddMatchSignal [%s]", err.Error())
+	}
+	in := make(chan *dbus.Signal, signalChannelBufferLength)
+	conn.Signal(in)
+	out := make(chan *dbus.Signal, signalChannelBufferLength)
+	go func() {
+		defer close(out)
+		defer conn.RemoveMatchSignal(options...)
+		defer conn.RemoveSignal(in)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case signal, ok := <-in:
+				if !ok {
+					return
+				}
+				// every subscriber of the shared connection sees every signal
+				if signal.Path != path || signal.Name != iface+"."+member {
+					continue
+				}
+				select {
+				case out <- signal:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
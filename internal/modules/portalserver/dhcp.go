@@ -0,0 +1,265 @@
+package portalserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+const (
+	dhcpServerPort = 67
+	dhcpClientPort = 68
+	// dhcpHeaderLength is the fixed BOOTP part of a message, up to the magic cookie included
+	dhcpHeaderLength = 240
+	// dhcpMinLength is the BOOTP message length some clients still expect
+	dhcpMinLength = 300
+	bootRequest   = 1
+	bootReply     = 2
+	// dhcpBroadcastFlag asks the server to broadcast its replies
+	dhcpBroadcastFlag = 0x8000
+)
+
+var dhcpMagicCookie = []byte{99, 130, 83, 99}
+
+// DHCP message types, option 53
+const (
+	dhcpDiscover byte = 1
+	dhcpOffer    byte = 2
+	dhcpRequest  byte = 3
+	dhcpDecline  byte = 4
+	dhcpAck      byte = 5
+	dhcpNak      byte = 6
+	dhcpRelease  byte = 7
+	dhcpInform   byte = 8
+)
+
+// DHCP options used by the portal
+const (
+	optionPad           byte = 0
+	optionSubnetMask    byte = 1
+	optionRouter        byte = 3
+	optionDNS           byte = 6
+	optionHostname      byte = 12
+	optionRequestedIP   byte = 50
+	optionLeaseTime     byte = 51
+	optionMessageType   byte = 53
+	optionServerID      byte = 54
+	optionRenewalTime   byte = 58
+	optionRebindingTime byte = 59
+	optionEnd           byte = 255
+)
+
+var dhcpMessageNames = map[byte]string{
+	dhcpDiscover: "DHCPDISCOVER",
+	dhcpOffer:    "DHCPOFFER",
+	dhcpRequest:  "DHCPREQUEST",
+	dhcpDecline:  "DHCPDECLINE",
+	dhcpAck:      "DHCPACK",
+	dhcpNak:      "DHCPNAK",
+	dhcpRelease:  "DHCPRELEASE",
+	dhcpInform:   "DHCPINFORM",
+}
+
+var errInvalidDHCPMessage = errors.New("invalid DHCP message")
+
+// dhcpMessage is a DHCP request of a client on an Ethernet-like link
+type dhcpMessage struct {
+	xid     []byte
+	flags   uint16
+	ciaddr  net.IP
+	giaddr  net.IP
+	chaddr  net.HardwareAddr
+	options map[byte][]byte
+}
+
+// parseDHCPMessage decodes a client request, options spread over the file and sname fields are not supported
+func parseDHCPMessage(data []byte) (msg *dhcpMessage, err error) {
+	if len(data) < dhcpHeaderLength || data[0] != bootRequest || data[1] != 1 || data[2] != 6 ||
+		!bytes.Equal(data[236:240], dhcpMagicCookie) {
+		return nil, errInvalidDHCPMessage
+	}
+	msg = &dhcpMessage{
+		xid:     append([]byte{}, data[4:8]...),
+		flags:   binary.BigEndian.Uint16(data[10:12]),
+		ciaddr:  net.IP(append([]byte{}, data[12:16]...)),
+		giaddr:  net.IP(append([]byte{}, data[24:28]...)),
+		chaddr:  net.HardwareAddr(append([]byte{}, data[28:34]...)),
+		options: make(map[byte][]byte),
+	}
+	for i := dhcpHeaderLength; i < len(data); {
+		code := data[i]
+		if code == optionEnd {
+			break
+		}
+		if code == optionPad {
+			i++
+			continue
+		}
+		if i+2 > len(data) || i+2+int(data[i+1]) > len(data) {
+			return nil, errInvalidDHCPMessage
+		}
+		length := int(data[i+1])
+		msg.options[code] = append(msg.options[code], data[i+2:i+2+length]...)
+		i += 2 + length
+	}
+	if len(msg.options[optionMessageType]) != 1 {
+		return nil, errInvalidDHCPMessage
+	}
+	return
+}
+
+func (m *dhcpMessage) messageType() byte {
+	return m.options[optionMessageType][0]
+}
+
+// requestedIP returns the address asked for in option 50, nil when absent
+func (m *dhcpMessage) requestedIP() net.IP {
+	if requested := m.options[optionRequestedIP]; len(requested) == net.IPv4len {
+		return net.IP(requested)
+	}
+	return nil
+}
+
+func (m *dhcpMessage) hostname() string {
+	return string(m.options[optionHostname])
+}
+
+// reply encodes the answer to the message, the message type and server ID first
+func (m *dhcpMessage) reply(msgType byte, yiaddr net.IP, serverID net.IP, options []dhcpOption) []byte {
+	data := make([]byte, dhcpHeaderLength, dhcpMinLength)
+	data[0] = bootReply
+	data[1], data[2] = 1, 6
+	copy(data[4:8], m.xid)
+	binary.BigEndian.PutUint16(data[10:12], m.flags)
+	if msgType != dhcpNak {
+		copy(data[12:16], m.ciaddr.To4())
+	}
+	if yiaddr != nil {
+		copy(data[16:20], yiaddr.To4())
+	}
+	copy(data[24:28], m.giaddr.To4())
+	copy(data[28:34], m.chaddr)
+	copy(data[236:240], dhcpMagicCookie)
+	options = append([]dhcpOption{{optionMessageType, []byte{msgType}}, {optionServerID, serverID.To4()}}, options...)
+	for _, option := range options {
+		data = append(data, option.code, byte(len(option.value)))
+		data = append(data, option.value...)
+	}
+	data = append(data, optionEnd)
+	for len(data) < dhcpMinLength {
+		data = append(data, optionPad)
+	}
+	return data
+}
+
+// destination returns where the reply is sent: renewing clients own their address already,
+// the others cannot be reached before their address is configured
+func (m *dhcpMessage) destination(msgType byte) *net.UDPAddr {
+	if msgType != dhcpNak && !m.ciaddr.Equal(net.IPv4zero) && m.flags&dhcpBroadcastFlag == 0 {
+		return &net.UDPAddr{IP: m.ciaddr, Port: dhcpClientPort}
+	}
+	return &net.UDPAddr{IP: net.IPv4bcast, Port: dhcpClientPort}
+}
+
+type dhcpOption struct {
+	code  byte
+	value []byte
+}
+
+// leaseOptions returns the network configuration given with an address
+func leaseOptions(subnet models.PortalSubnet, withLease bool) []dhcpOption {
+	options := []dhcpOption{
+		{optionSubnetMask, []byte(subnet.Subnet.Mask)},
+		{optionRouter, subnet.Gateway.To4()},
+		{optionDNS, subnet.Gateway.To4()},
+	}
+	if withLease {
+		options = append(options,
+			dhcpOption{optionLeaseTime, seconds(leaseDuration.Seconds())},
+			dhcpOption{optionRenewalTime, seconds(leaseDuration.Seconds() / 2)},
+			dhcpOption{optionRebindingTime, seconds(leaseDuration.Seconds() * 7 / 8)},
+		)
+	}
+	return options
+}
+
+func seconds(s float64) []byte {
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, uint32(s))
+	return value
+}
+
+// serveDHCP answers the DHCP requests until the socket is closed
+func (p *PortalServer) serveDHCP(conn *net.UDPConn, subnet models.PortalSubnet, leases *leasePool) {
+	defer p.wg.Done()
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			p.Log.Error(fmt.Sprintf("serveDHCP - found error on ReadFromUDP [%s]", err.Error()))
+			continue
+		}
+		msg, err := parseDHCPMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		msgType, reply := p.handleDHCP(msg, subnet, leases)
+		if reply == nil {
+			continue
+		}
+		_, err = conn.WriteToUDP(reply, msg.destination(msgType))
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("serveDHCP - found error on sending %s to %s [%s]", dhcpMessageNames[msgType], msg.chaddr, err.Error()))
+		}
+	}
+}
+
+// handleDHCP returns the reply to the message, nil when none is due
+func (p *PortalServer) handleDHCP(msg *dhcpMessage, subnet models.PortalSubnet, leases *leasePool) (msgType byte, reply []byte) {
+	mac := msg.chaddr.String()
+	if !msg.giaddr.Equal(net.IPv4zero) {
+		// relayed requests come from other networks, the portal only serves its own link
+		return
+	}
+	switch msg.messageType() {
+	case dhcpDiscover:
+		ip := leases.offer(mac, msg.requestedIP(), msg.hostname())
+		if ip == nil {
+			p.Log.Warn(fmt.Sprintf("DHCPDISCOVER(%s) - no address available for %s", subnet.Gateway, mac))
+			return
+		}
+		msgType, reply = dhcpOffer, msg.reply(dhcpOffer, ip, subnet.Gateway, leaseOptions(subnet, true))
+		p.Log.Info(fmt.Sprintf("DHCPOFFER(%s) %s %s", subnet.Gateway, ip, mac))
+	case dhcpRequest:
+		if serverID := msg.options[optionServerID]; serverID != nil && !net.IP(serverID).Equal(subnet.Gateway) {
+			leases.cancelOffer(mac)
+			return
+		}
+		requested := msg.requestedIP()
+		if requested == nil {
+			requested = msg.ciaddr
+		}
+		if _, ok := leases.ack(mac, requested, msg.hostname()); !ok {
+			p.Log.Info(fmt.Sprintf("DHCPNAK(%s) %s %s address not available", subnet.Gateway, requested, mac))
+			return dhcpNak, msg.reply(dhcpNak, nil, subnet.Gateway, nil)
+		}
+		msgType, reply = dhcpAck, msg.reply(dhcpAck, requested, subnet.Gateway, leaseOptions(subnet, true))
+		p.Log.Info(fmt.Sprintf("DHCPACK(%s) %s %s %s", subnet.Gateway, requested, mac, msg.hostname()))
+	case dhcpDecline:
+		leases.decline(mac, msg.requestedIP())
+		p.Log.Warn(fmt.Sprintf("DHCPDECLINE(%s) %s %s address already in use", subnet.Gateway, msg.requestedIP(), mac))
+	case dhcpRelease:
+		leases.release(mac, msg.ciaddr)
+		p.Log.Info(fmt.Sprintf("DHCPRELEASE(%s) %s %s", subnet.Gateway, msg.ciaddr, mac))
+	case dhcpInform:
+		// the client configured its address itself and only asks for the other settings
+		msgType, reply = dhcpAck, msg.reply(dhcpAck, nil, subnet.Gateway, leaseOptions(subnet, false))
+	}
+	return
+}
@@ -0,0 +1,300 @@
+package portalserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+var testMAC = net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0xbe, 0xef}
+
+// testSubnet returns the default portal network, a DHCP range of count addresses from 192.168.42.2
+func testSubnet(count int) models.PortalSubnet {
+	_, network, _ := net.ParseCIDR("192.168.42.0/24")
+	return models.PortalSubnet{
+		Gateway:   net.IPv4(192, 168, 42, 1).To4(),
+		Subnet:    network,
+		DHCPStart: net.IPv4(192, 168, 42, 2).To4(),
+		DHCPEnd:   net.IPv4(192, 168, 42, byte(1+count)).To4(),
+	}
+}
+
+func testPortalServer() *PortalServer {
+	l := logrus.New()
+	l.Out = io.Discard
+	return NewPortalServer(l, &models.Config{})
+}
+
+// dhcpPacket encodes a client request from the MAC, options are terminated by End
+func dhcpPacket(mac net.HardwareAddr, options ...dhcpOption) []byte {
+	data := make([]byte, dhcpHeaderLength)
+	data[0], data[1], data[2] = bootRequest, 1, 6
+	copy(data[4:8], []byte{0xde, 0xad, 0xbe, 0xef})
+	copy(data[28:34], mac)
+	copy(data[236:240], dhcpMagicCookie)
+	for _, option := range options {
+		data = append(data, option.code, byte(len(option.value)))
+		data = append(data, option.value...)
+	}
+	return append(data, optionEnd)
+}
+
+func messageType(msgType byte) dhcpOption {
+	return dhcpOption{optionMessageType, []byte{msgType}}
+}
+
+func TestParseDHCPMessage(t *testing.T) {
+	discover := dhcpPacket(testMAC, messageType(dhcpDiscover), dhcpOption{optionHostname, []byte("phone")})
+	tests := []struct {
+		name string
+		data []byte
+		// change alters a copy of data before parsing
+		change       func(data []byte) []byte
+		wantErr      bool
+		wantType     byte
+		wantHostname string
+	}{
+		{name: "discover", data: discover, wantType: dhcpDiscover, wantHostname: "phone"},
+		{
+			name:     "padded",
+			data:     discover,
+			change:   func(data []byte) []byte { return append(data, make([]byte, 60)...) },
+			wantType: dhcpDiscover, wantHostname: "phone",
+		},
+		{
+			name: "pad options",
+			data: dhcpPacket(testMAC, dhcpOption{optionPad, nil}, messageType(dhcpRequest)),
+			change: func(data []byte) []byte {
+				// the pad option is a single byte, drop its length
+				return append(data[:dhcpHeaderLength+1], data[dhcpHeaderLength+2:]...)
+			},
+			wantType: dhcpRequest,
+		},
+		{
+			name:     "options after end ignored",
+			data:     discover,
+			change:   func(data []byte) []byte { return append(data, optionHostname, 40) },
+			wantType: dhcpDiscover, wantHostname: "phone",
+		},
+		{
+			name:     "option split in several parts",
+			data:     dhcpPacket(testMAC, messageType(dhcpDiscover), dhcpOption{optionHostname, []byte("pho")}, dhcpOption{optionHostname, []byte("ne")}),
+			wantType: dhcpDiscover, wantHostname: "phone",
+		},
+		{name: "empty", data: nil, wantErr: true},
+		{name: "truncated header", data: discover[:dhcpHeaderLength-1], wantErr: true},
+		{name: "truncated options", data: discover[:dhcpHeaderLength+4], wantErr: true},
+		{name: "option code without length", data: discover, change: func(data []byte) []byte { return append(data[:len(data)-1], optionHostname) }, wantErr: true},
+		{name: "option overflowing the packet", data: discover, change: func(data []byte) []byte { data[dhcpHeaderLength+4] = 200; return data }, wantErr: true},
+		{name: "reply", data: discover, change: func(data []byte) []byte { data[0] = bootReply; return data }, wantErr: true},
+		{name: "not Ethernet", data: discover, change: func(data []byte) []byte { data[1] = 6; return data }, wantErr: true},
+		{name: "wrong hardware address length", data: discover, change: func(data []byte) []byte { data[2] = 8; return data }, wantErr: true},
+		{name: "BOOTP without magic cookie", data: discover, change: func(data []byte) []byte { data[239] = 0; return data }, wantErr: true},
+		{name: "no message type", data: dhcpPacket(testMAC, dhcpOption{optionHostname, []byte("phone")}), wantErr: true},
+		{name: "message type too long", data: dhcpPacket(testMAC, dhcpOption{optionMessageType, []byte{dhcpDiscover, 0}}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append([]byte{}, tt.data...)
+			if tt.change != nil {
+				data = tt.change(data)
+			}
+			msg, err := parseDHCPMessage(data)
+			if tt.wantErr {
+				if err != errInvalidDHCPMessage {
+					t.Fatalf("parseDHCPMessage returned error %v, want %v", err, errInvalidDHCPMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDHCPMessage: %s", err.Error())
+			}
+			if msg.messageType() != tt.wantType || msg.hostname() != tt.wantHostname {
+				t.Errorf("message %d from %q, want %d from %q", msg.messageType(), msg.hostname(), tt.wantType, tt.wantHostname)
+			}
+			if !bytes.Equal(msg.chaddr, testMAC) || !bytes.Equal(msg.xid, []byte{0xde, 0xad, 0xbe, 0xef}) {
+				t.Errorf("chaddr %s xid %x", msg.chaddr, msg.xid)
+			}
+		})
+	}
+}
+
+// dhcpReply is the part of a reply checked by the tests
+type dhcpReply struct {
+	msgType byte
+	yiaddr  net.IP
+	options map[byte][]byte
+}
+
+func parseDHCPReply(t *testing.T, data []byte) dhcpReply {
+	t.Helper()
+	// parse the reply as a request to reuse the option decoding
+	request := append([]byte{}, data...)
+	if request[0] != bootReply || len(request) < dhcpMinLength {
+		t.Fatalf("reply op %d, %d bytes", request[0], len(request))
+	}
+	request[0] = bootRequest
+	msg, err := parseDHCPMessage(request)
+	if err != nil {
+		t.Fatalf("parsing the reply: %s", err.Error())
+	}
+	return dhcpReply{msgType: msg.messageType(), yiaddr: net.IP(data[16:20]), options: msg.options}
+}
+
+func TestHandleDHCP(t *testing.T) {
+	subnet := testSubnet(2)
+	gateway := []byte(subnet.Gateway)
+	otherMAC := net.HardwareAddr{0x02, 0x00, 0x00, 0x00, 0x00, 0x02}
+	relayed := dhcpPacket(testMAC, messageType(dhcpDiscover))
+	copy(relayed[24:28], []byte{10, 0, 0, 1})
+	tests := []struct {
+		name   string
+		packet []byte
+		// wantType is 0 when no reply is due
+		wantType   byte
+		wantYiaddr string
+		wantLease  bool
+		// wantLeases lists the MAC and address of the bound leases after the message
+		wantLeases []string
+	}{
+		{
+			name:       "discover",
+			packet:     dhcpPacket(testMAC, messageType(dhcpDiscover)),
+			wantType:   dhcpOffer,
+			wantYiaddr: "192.168.42.2",
+			wantLease:  true,
+		},
+		{
+			name:   "relayed discover",
+			packet: relayed,
+		},
+		{
+			name:       "request of the offer",
+			packet:     dhcpPacket(testMAC, messageType(dhcpRequest), dhcpOption{optionRequestedIP, []byte{192, 168, 42, 2}}, dhcpOption{optionServerID, gateway}),
+			wantType:   dhcpAck,
+			wantYiaddr: "192.168.42.2",
+			wantLease:  true,
+			wantLeases: []string{testMAC.String() + " 192.168.42.2"},
+		},
+		{
+			name:       "request of an address leased to another client",
+			packet:     dhcpPacket(otherMAC, messageType(dhcpRequest), dhcpOption{optionRequestedIP, []byte{192, 168, 42, 2}}),
+			wantType:   dhcpNak,
+			wantYiaddr: "0.0.0.0",
+			wantLeases: []string{testMAC.String() + " 192.168.42.2"},
+		},
+		{
+			name:       "request of an address outside the range",
+			packet:     dhcpPacket(otherMAC, messageType(dhcpRequest), dhcpOption{optionRequestedIP, []byte{10, 42, 0, 5}}),
+			wantType:   dhcpNak,
+			wantYiaddr: "0.0.0.0",
+			wantLeases: []string{testMAC.String() + " 192.168.42.2"},
+		},
+		{
+			name:       "discover of a second client",
+			packet:     dhcpPacket(otherMAC, messageType(dhcpDiscover), dhcpOption{optionRequestedIP, []byte{192, 168, 42, 2}}),
+			wantType:   dhcpOffer,
+			wantYiaddr: "192.168.42.3",
+			wantLease:  true,
+			wantLeases: []string{testMAC.String() + " 192.168.42.2"},
+		},
+		{
+			name:       "request for another server",
+			packet:     dhcpPacket(otherMAC, messageType(dhcpRequest), dhcpOption{optionRequestedIP, []byte{10, 0, 0, 9}}, dhcpOption{optionServerID, []byte{10, 0, 0, 1}}),
+			wantLeases: []string{testMAC.String() + " 192.168.42.2"},
+		},
+		{
+			name:       "inform",
+			packet:     dhcpPacket(otherMAC, messageType(dhcpInform)),
+			wantType:   dhcpAck,
+			wantYiaddr: "0.0.0.0",
+			wantLeases: []string{testMAC.String() + " 192.168.42.2"},
+		},
+		{
+			name: "release",
+			packet: func() []byte {
+				packet := dhcpPacket(testMAC, messageType(dhcpRelease))
+				copy(packet[12:16], []byte{192, 168, 42, 2})
+				return packet
+			}(),
+		},
+	}
+	p := testPortalServer()
+	leases, err := newLeasePool(subnet)
+	if err != nil {
+		t.Fatalf("newLeasePool: %s", err.Error())
+	}
+	// the steps run in order on the same pool
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseDHCPMessage(tt.packet)
+			if err != nil {
+				t.Fatalf("parseDHCPMessage: %s", err.Error())
+			}
+			msgType, reply := p.handleDHCP(msg, subnet, leases)
+			if tt.wantType == 0 {
+				if reply != nil {
+					t.Fatalf("reply %s, want none", dhcpMessageNames[msgType])
+				}
+			} else {
+				if reply == nil || msgType != tt.wantType {
+					t.Fatalf("reply %s, want %s", dhcpMessageNames[msgType], dhcpMessageNames[tt.wantType])
+				}
+				got := parseDHCPReply(t, reply)
+				if got.msgType != tt.wantType || got.yiaddr.String() != tt.wantYiaddr {
+					t.Errorf("%s for %s, want %s for %s", dhcpMessageNames[got.msgType], got.yiaddr, dhcpMessageNames[tt.wantType], tt.wantYiaddr)
+				}
+				if !bytes.Equal(got.options[optionServerID], gateway) {
+					t.Errorf("server ID %v, want %v", got.options[optionServerID], gateway)
+				}
+				if leaseTime := got.options[optionLeaseTime]; (leaseTime != nil) != tt.wantLease ||
+					tt.wantLease && binary.BigEndian.Uint32(leaseTime) != uint32(leaseDuration.Seconds()) {
+					t.Errorf("lease time %v, want a lease %t", leaseTime, tt.wantLease)
+				}
+				if tt.wantType != dhcpNak && !bytes.Equal(got.options[optionRouter], gateway) {
+					t.Errorf("router %v, want %v", got.options[optionRouter], gateway)
+				}
+			}
+			var bound []string
+			for _, current := range leases.list() {
+				bound = append(bound, current.MAC+" "+current.IP)
+			}
+			if len(bound) != len(tt.wantLeases) {
+				t.Fatalf("leases %v, want %v", bound, tt.wantLeases)
+			}
+			for i := range bound {
+				if bound[i] != tt.wantLeases[i] {
+					t.Errorf("leases %v, want %v", bound, tt.wantLeases)
+				}
+			}
+		})
+	}
+}
+
+func TestDHCPDestination(t *testing.T) {
+	tests := []struct {
+		name    string
+		ciaddr  net.IP
+		flags   uint16
+		msgType byte
+		want    string
+	}{
+		{name: "new client", ciaddr: net.IPv4zero, msgType: dhcpOffer, want: "255.255.255.255:68"},
+		{name: "renewing client", ciaddr: net.IPv4(192, 168, 42, 2), msgType: dhcpAck, want: "192.168.42.2:68"},
+		{name: "broadcast flag", ciaddr: net.IPv4(192, 168, 42, 2), flags: dhcpBroadcastFlag, msgType: dhcpAck, want: "255.255.255.255:68"},
+		{name: "refused renewal", ciaddr: net.IPv4(192, 168, 42, 2), msgType: dhcpNak, want: "255.255.255.255:68"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &dhcpMessage{ciaddr: tt.ciaddr, flags: tt.flags}
+			if got := msg.destination(tt.msgType).String(); got != tt.want {
+				t.Errorf("destination %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
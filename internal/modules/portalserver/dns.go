@@ -0,0 +1,115 @@
+package portalserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const dnsPort = 53
+
+// dnsTTL of 0 keeps clients from caching the portal address once they joined another network
+const dnsTTL = 0
+
+const (
+	dnsHeaderLength = 12
+	dnsTypeA        = 1
+	dnsTypeANY      = 255
+	dnsClassIN      = 1
+	dnsRcodeFormErr = 1
+	dnsRcodeNotImp  = 4
+)
+
+var errInvalidDNSQuery = errors.New("invalid DNS query")
+
+// serveDNS answers the DNS queries until the socket is closed
+func (p *PortalServer) serveDNS(conn *net.UDPConn, gateway net.IP) {
+	defer p.wg.Done()
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			p.Log.Error(fmt.Sprintf("serveDNS - found error on ReadFromUDP [%s]", err.Error()))
+			continue
+		}
+		answer, err := dnsAnswer(buf[:n], gateway)
+		if err != nil {
+			continue
+		}
+		_, err = conn.WriteToUDP(answer, addr)
+		if err != nil {
+			p.Log.Error(fmt.Sprintf("serveDNS - found error on WriteToUDP to %s [%s]", addr, err.Error()))
+		}
+	}
+}
+
+// dnsAnswer returns the answer to a query: the gateway for every A query, no record for the other types
+// so clients fall back to IPv4. Responses are never queries, they get no answer.
+func dnsAnswer(query []byte, gateway net.IP) (answer []byte, err error) {
+	if len(query) < dnsHeaderLength || query[2]&0x80 != 0 {
+		return nil, errInvalidDNSQuery
+	}
+	flags := binary.BigEndian.Uint16(query[2:4])
+	opcode := flags >> 11 & 0xF
+	// QR, the query opcode, authoritative, recursion desired as asked, recursion available
+	answerFlags := 0x8000 | flags&0x7900 | 0x0400 | 0x0080
+	header := func(rcode uint16, questions uint16, answers uint16) []byte {
+		h := make([]byte, dnsHeaderLength)
+		copy(h[0:2], query[0:2])
+		binary.BigEndian.PutUint16(h[2:4], answerFlags|rcode)
+		binary.BigEndian.PutUint16(h[4:6], questions)
+		binary.BigEndian.PutUint16(h[6:8], answers)
+		return h
+	}
+	if opcode != 0 {
+		return header(dnsRcodeNotImp, 0, 0), nil
+	}
+	if binary.BigEndian.Uint16(query[4:6]) != 1 {
+		return header(dnsRcodeFormErr, 0, 0), nil
+	}
+	end, err := questionEnd(query)
+	if err != nil {
+		return header(dnsRcodeFormErr, 0, 0), nil
+	}
+	qtype := binary.BigEndian.Uint16(query[end-4 : end-2])
+	qclass := binary.BigEndian.Uint16(query[end-2 : end])
+	if qclass != dnsClassIN || qtype != dnsTypeA && qtype != dnsTypeANY {
+		return append(header(0, 1, 0), query[dnsHeaderLength:end]...), nil
+	}
+	answer = append(header(0, 1, 1), query[dnsHeaderLength:end]...)
+	record := make([]byte, 16)
+	// the name is a pointer to the question
+	binary.BigEndian.PutUint16(record[0:2], 0xC000|dnsHeaderLength)
+	binary.BigEndian.PutUint16(record[2:4], dnsTypeA)
+	binary.BigEndian.PutUint16(record[4:6], dnsClassIN)
+	binary.BigEndian.PutUint32(record[6:10], dnsTTL)
+	binary.BigEndian.PutUint16(record[10:12], net.IPv4len)
+	copy(record[12:16], gateway.To4())
+	return append(answer, record...), nil
+}
+
+// questionEnd returns the offset following the question, its name made of uncompressed labels
+func questionEnd(query []byte) (int, error) {
+	i := dnsHeaderLength
+	for {
+		if i >= len(query) {
+			return 0, errInvalidDNSQuery
+		}
+		length := int(query[i])
+		if length == 0 {
+			break
+		}
+		if length&0xC0 != 0 {
+			return 0, errInvalidDNSQuery
+		}
+		i += 1 + length
+	}
+	i += 1 + 4
+	if i > len(query) {
+		return 0, errInvalidDNSQuery
+	}
+	return i, nil
+}
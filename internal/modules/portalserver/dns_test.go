@@ -0,0 +1,133 @@
+package portalserver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+)
+
+const dnsTypeAAAA = 28
+
+// dnsQuery encodes a query with recursion desired for the name
+func dnsQuery(name string, qtype uint16, qclass uint16) []byte {
+	query := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		query = append(query, byte(len(label)))
+		query = append(query, label...)
+	}
+	query = append(query, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(query[len(query)-4:], qtype)
+	binary.BigEndian.PutUint16(query[len(query)-2:], qclass)
+	return query
+}
+
+func TestDNSAnswer(t *testing.T) {
+	gateway := net.IPv4(192, 168, 42, 1)
+	query := dnsQuery("connectivitycheck.gstatic.com", dnsTypeA, dnsClassIN)
+	tests := []struct {
+		name  string
+		query []byte
+		// change alters a copy of the query before answering
+		change      func(query []byte) []byte
+		wantErr     bool
+		wantRcode   uint16
+		wantAnswers uint16
+		// wantQuestion tells whether the question is echoed
+		wantQuestion bool
+	}{
+		{name: "A", query: query, wantAnswers: 1, wantQuestion: true},
+		{name: "ANY", query: dnsQuery("example.com", dnsTypeANY, dnsClassIN), wantAnswers: 1, wantQuestion: true},
+		{name: "root", query: dnsQuery(".", dnsTypeA, dnsClassIN), wantAnswers: 1, wantQuestion: true},
+		{name: "AAAA", query: dnsQuery("example.com", dnsTypeAAAA, dnsClassIN), wantQuestion: true},
+		{name: "CHAOS class", query: dnsQuery("version.bind", 16, 3), wantQuestion: true},
+		{
+			name:         "with additional records",
+			query:        query,
+			change:       func(query []byte) []byte { query[11] = 1; return append(query, 0, 0, 41, 16, 0, 0, 0, 0, 0, 0, 0) },
+			wantAnswers:  1,
+			wantQuestion: true,
+		},
+		{name: "status opcode", query: query, change: func(query []byte) []byte { query[2] |= 2 << 3; return query }, wantRcode: dnsRcodeNotImp},
+		{name: "no question", query: query, change: func(query []byte) []byte { query[5] = 0; return query }, wantRcode: dnsRcodeFormErr},
+		{name: "two questions", query: query, change: func(query []byte) []byte { query[5] = 2; return query }, wantRcode: dnsRcodeFormErr},
+		{name: "truncated name", query: query[:20], wantRcode: dnsRcodeFormErr},
+		{name: "truncated type", query: query[:len(query)-1], wantRcode: dnsRcodeFormErr},
+		{
+			name:      "compressed name",
+			query:     query,
+			change:    func(query []byte) []byte { return append(query[:dnsHeaderLength], 0xC0, 0x0C, 0, 1, 0, 1) },
+			wantRcode: dnsRcodeFormErr,
+		},
+		{
+			name:      "label overflowing the packet",
+			query:     query,
+			change:    func(query []byte) []byte { query[dnsHeaderLength] = 63; return query },
+			wantRcode: dnsRcodeFormErr,
+		},
+		{name: "header only", query: query[:dnsHeaderLength], wantRcode: dnsRcodeFormErr},
+		{name: "truncated header", query: query[:dnsHeaderLength-1], wantErr: true},
+		{name: "empty", wantErr: true},
+		{name: "response", query: query, change: func(query []byte) []byte { query[2] |= 0x80; return query }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := append([]byte{}, tt.query...)
+			if tt.change != nil {
+				q = tt.change(q)
+			}
+			answer, err := dnsAnswer(q, gateway)
+			if tt.wantErr {
+				if err != errInvalidDNSQuery {
+					t.Fatalf("dnsAnswer returned error %v, want %v", err, errInvalidDNSQuery)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dnsAnswer: %s", err.Error())
+			}
+			if len(answer) < dnsHeaderLength {
+				t.Fatalf("answer of %d bytes", len(answer))
+			}
+			flags := binary.BigEndian.Uint16(answer[2:4])
+			if !bytes.Equal(answer[0:2], q[0:2]) || flags&0x8000 == 0 || flags&0x0100 == 0 {
+				t.Errorf("answer id %x flags %016b, want id %x, response with recursion desired", answer[0:2], flags, q[0:2])
+			}
+			if rcode := flags & 0xF; rcode != tt.wantRcode {
+				t.Errorf("rcode %d, want %d", rcode, tt.wantRcode)
+			}
+			if answers := binary.BigEndian.Uint16(answer[6:8]); answers != tt.wantAnswers {
+				t.Errorf("%d answers, want %d", answers, tt.wantAnswers)
+			}
+			questions := binary.BigEndian.Uint16(answer[4:6])
+			if tt.wantQuestion != (questions == 1) {
+				t.Fatalf("%d questions echoed, want the question %t", questions, tt.wantQuestion)
+			}
+			end := dnsHeaderLength
+			if tt.wantQuestion {
+				end, _ = questionEnd(q)
+				if !bytes.Equal(answer[dnsHeaderLength:end], q[dnsHeaderLength:end]) {
+					t.Errorf("question %x, want %x", answer[dnsHeaderLength:end], q[dnsHeaderLength:end])
+				}
+			}
+			if tt.wantAnswers == 0 {
+				if len(answer) != end {
+					t.Errorf("answer of %d bytes, want %d", len(answer), end)
+				}
+				return
+			}
+			record := answer[end:]
+			if len(record) != 16 || binary.BigEndian.Uint16(record[0:2]) != 0xC000|dnsHeaderLength ||
+				binary.BigEndian.Uint16(record[2:4]) != dnsTypeA || binary.BigEndian.Uint32(record[6:10]) != dnsTTL {
+				t.Fatalf("record %x", record)
+			}
+			if ip := net.IP(record[12:16]); !ip.Equal(gateway) {
+				t.Errorf("address %s, want %s", ip, gateway)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package portalserver
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// leaseDuration is the lease time given to clients, the same as the dnsmasq default
+const leaseDuration = time.Hour
+
+// offerTimeout is how long an offered address stays reserved for the client's request
+const offerTimeout = time.Minute
+
+// lease is an address offered or leased to a client, declined addresses have no MAC
+type lease struct {
+	mac      string
+	hostname string
+	expires  time.Time
+	bound    bool
+}
+
+// leasePool hands out the addresses of the DHCP range, the gateway left out
+type leasePool struct {
+	mu      sync.Mutex
+	start   uint32
+	end     uint32
+	gateway uint32
+	leases  map[uint32]*lease
+}
+
+func newLeasePool(subnet models.PortalSubnet) (*leasePool, error) {
+	start, end := ip4ToUint32(subnet.DHCPStart), ip4ToUint32(subnet.DHCPEnd)
+	if subnet.DHCPStart.To4() == nil || subnet.DHCPEnd.To4() == nil || start > end {
+		return nil, fmt.Errorf("invalid DHCP range %s - %s", subnet.DHCPStart, subnet.DHCPEnd)
+	}
+	return &leasePool{
+		start:   start,
+		end:     end,
+		gateway: ip4ToUint32(subnet.Gateway),
+		leases:  make(map[uint32]*lease),
+	}, nil
+}
+
+// offer reserves an address for the client: the one it already has, the requested one when free,
+// or the first free one. nil is returned when the range is exhausted.
+func (l *leasePool) offer(mac string, requested net.IP, hostname string) net.IP {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expire(time.Now())
+	ip, found := l.addressOf(mac)
+	if !found {
+		ip, found = l.freeAddress(ip4ToUint32(requested))
+	}
+	if !found {
+		return nil
+	}
+	if current := l.leases[ip]; current == nil || !current.bound {
+		l.leases[ip] = &lease{mac: mac, hostname: hostname, expires: time.Now().Add(offerTimeout)}
+	}
+	return uint32ToIP4(ip)
+}
+
+// ack binds the requested address to the client, false when it is out of the range or used by another client
+func (l *leasePool) ack(mac string, requested net.IP, hostname string) (expires time.Time, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.expire(now)
+	ip := ip4ToUint32(requested)
+	if !l.inRange(ip) {
+		return
+	}
+	if current, found := l.leases[ip]; found && current.mac != mac {
+		return
+	}
+	// a client holds a single address
+	if previous, found := l.addressOf(mac); found && previous != ip {
+		delete(l.leases, previous)
+	}
+	expires = now.Add(leaseDuration)
+	l.leases[ip] = &lease{mac: mac, hostname: hostname, expires: expires, bound: true}
+	return expires, true
+}
+
+// release frees the address of the client
+func (l *leasePool) release(mac string, ip net.IP) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if current, found := l.leases[ip4ToUint32(ip)]; found && current.mac == mac {
+		delete(l.leases, ip4ToUint32(ip))
+	}
+}
+
+// decline keeps an address another host already uses out of the pool for a lease time
+func (l *leasePool) decline(mac string, ip net.IP) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	address := ip4ToUint32(ip)
+	if current, found := l.leases[address]; found && current.mac == mac {
+		l.leases[address] = &lease{expires: time.Now().Add(leaseDuration)}
+	}
+}
+
+// cancelOffer frees the address offered to a client which accepted another server's offer
+func (l *leasePool) cancelOffer(mac string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ip, found := l.addressOf(mac); found && !l.leases[ip].bound {
+		delete(l.leases, ip)
+	}
+}
+
+// list returns the bound leases, ordered by address
+func (l *leasePool) list() []models.Lease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.expire(time.Now())
+	addresses := make([]uint32, 0, len(l.leases))
+	for ip, current := range l.leases {
+		if current.bound {
+			addresses = append(addresses, ip)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	leases := make([]models.Lease, len(addresses))
+	for i, ip := range addresses {
+		current := l.leases[ip]
+		leases[i] = models.Lease{
+			MAC:      current.mac,
+			IP:       uint32ToIP4(ip).String(),
+			Hostname: current.hostname,
+			Expires:  current.expires,
+		}
+	}
+	return leases
+}
+
+func (l *leasePool) expire(now time.Time) {
+	for ip, current := range l.leases {
+		if now.After(current.expires) {
+			delete(l.leases, ip)
+		}
+	}
+}
+
+func (l *leasePool) addressOf(mac string) (uint32, bool) {
+	for ip, current := range l.leases {
+		if current.mac == mac {
+			return ip, true
+		}
+	}
+	return 0, false
+}
+
+// freeAddress returns the preferred address when free, the first free one of the range otherwise
+func (l *leasePool) freeAddress(preferred uint32) (uint32, bool) {
+	if l.isFree(preferred) {
+		return preferred, true
+	}
+	for ip := uint64(l.start); ip <= uint64(l.end); ip++ {
+		if l.isFree(uint32(ip)) {
+			return uint32(ip), true
+		}
+	}
+	return 0, false
+}
+
+func (l *leasePool) inRange(ip uint32) bool {
+	return ip >= l.start && ip <= l.end && ip != l.gateway
+}
+
+func (l *leasePool) isFree(ip uint32) bool {
+	_, used := l.leases[ip]
+	return l.inRange(ip) && !used
+}
+
+// ip4ToUint32 returns the IPv4 address as a number, 0 when it is not an IPv4 address
+func ip4ToUint32(ip net.IP) uint32 {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(ip4)
+}
+
+func uint32ToIP4(n uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
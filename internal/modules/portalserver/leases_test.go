@@ -0,0 +1,227 @@
+package portalserver
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func testMACs(n int) (macs []string) {
+	for i := 0; i < n; i++ {
+		macs = append(macs, fmt.Sprintf("02:00:00:00:00:%02x", i+1))
+	}
+	return
+}
+
+func newTestLeasePool(t *testing.T, count int) *leasePool {
+	t.Helper()
+	leases, err := newLeasePool(testSubnet(count))
+	if err != nil {
+		t.Fatalf("newLeasePool: %s", err.Error())
+	}
+	return leases
+}
+
+func TestLeasePoolExhaustion(t *testing.T) {
+	leases := newTestLeasePool(t, 3)
+	macs := testMACs(4)
+	for i, mac := range macs[:3] {
+		ip := leases.offer(mac, nil, "")
+		if want := fmt.Sprintf("192.168.42.%d", i+2); ip.String() != want {
+			t.Fatalf("offer to %s is %s, want %s", mac, ip, want)
+		}
+	}
+	if ip := leases.offer(macs[3], nil, ""); ip != nil {
+		t.Fatalf("offer %s from an exhausted range", ip)
+	}
+	// a client asking again keeps its address
+	if ip := leases.offer(macs[1], nil, ""); ip.String() != "192.168.42.3" {
+		t.Errorf("second offer to %s is %s, want 192.168.42.3", macs[1], ip)
+	}
+
+	leases.cancelOffer(macs[0])
+	if ip := leases.offer(macs[3], net.IPv4(192, 168, 42, 4), ""); ip.String() != "192.168.42.2" {
+		t.Errorf("offer after a cancelled offer is %s, want 192.168.42.2", ip)
+	}
+}
+
+func TestLeasePoolGatewayInRange(t *testing.T) {
+	subnet := testSubnet(2)
+	subnet.DHCPStart = subnet.Gateway
+	leases, err := newLeasePool(subnet)
+	if err != nil {
+		t.Fatalf("newLeasePool: %s", err.Error())
+	}
+	macs := testMACs(3)
+	if ip := leases.offer(macs[0], subnet.Gateway, ""); ip.String() != "192.168.42.2" {
+		t.Errorf("offer %s, want 192.168.42.2", ip)
+	}
+	if ip := leases.offer(macs[1], nil, ""); ip.String() != "192.168.42.3" {
+		t.Errorf("offer %s, want 192.168.42.3", ip)
+	}
+	if ip := leases.offer(macs[2], nil, ""); ip != nil {
+		t.Errorf("offer %s, the gateway is never leased", ip)
+	}
+	if _, ok := leases.ack(macs[2], subnet.Gateway, ""); ok {
+		t.Errorf("gateway acknowledged")
+	}
+}
+
+func TestLeasePoolExpiry(t *testing.T) {
+	leases := newTestLeasePool(t, 1)
+	macs := testMACs(2)
+	if ip := leases.offer(macs[0], nil, ""); ip == nil {
+		t.Fatalf("no address offered")
+	}
+	if ip := leases.offer(macs[1], nil, ""); ip != nil {
+		t.Fatalf("offer %s of a reserved address", ip)
+	}
+	// the client never requests the offered address
+	leases.expire(time.Now().Add(offerTimeout + time.Second))
+	ip := leases.offer(macs[1], nil, "")
+	if ip.String() != "192.168.42.2" {
+		t.Fatalf("offer after the offer timeout is %s, want 192.168.42.2", ip)
+	}
+
+	expires, ok := leases.ack(macs[1], ip, "laptop")
+	if !ok {
+		t.Fatalf("request refused")
+	}
+	if d := time.Until(expires); d < leaseDuration-time.Minute || d > leaseDuration {
+		t.Errorf("lease expires in %s, want %s", d, leaseDuration)
+	}
+	leases.expire(time.Now().Add(offerTimeout + time.Second))
+	if got := leases.list(); len(got) != 1 || got[0].MAC != macs[1] || got[0].Hostname != "laptop" {
+		t.Fatalf("bound lease dropped at the offer timeout, leases %v", got)
+	}
+	leases.expire(time.Now().Add(leaseDuration + time.Second))
+	if got := leases.list(); len(got) != 0 {
+		t.Errorf("leases %v after the lease time", got)
+	}
+	if _, ok := leases.ack(macs[0], ip, ""); !ok {
+		t.Errorf("expired address refused")
+	}
+}
+
+func TestLeasePoolAck(t *testing.T) {
+	first := net.IPv4(192, 168, 42, 2)
+	second := net.IPv4(192, 168, 42, 3)
+	macs := testMACs(2)
+	tests := []struct {
+		name string
+		// prepare runs on a pool of 3 addresses before the request of macs[0] for the address
+		prepare func(leases *leasePool)
+		address net.IP
+		wantOK  bool
+		// wantLeases holds the bound addresses by MAC after the request
+		wantLeases map[string]string
+	}{
+		{
+			name:       "address offered to the client",
+			prepare:    func(leases *leasePool) { leases.offer(macs[0], nil, "") },
+			address:    first,
+			wantOK:     true,
+			wantLeases: map[string]string{macs[0]: "192.168.42.2"},
+		},
+		{
+			name:       "init-reboot without offer",
+			address:    second,
+			wantOK:     true,
+			wantLeases: map[string]string{macs[0]: "192.168.42.3"},
+		},
+		{
+			name:       "address offered to another client",
+			prepare:    func(leases *leasePool) { leases.offer(macs[1], first, "") },
+			address:    first,
+			wantLeases: map[string]string{},
+		},
+		{
+			name:       "address leased to another client",
+			prepare:    func(leases *leasePool) { leases.ack(macs[1], first, "") },
+			address:    first,
+			wantLeases: map[string]string{macs[1]: "192.168.42.2"},
+		},
+		{
+			name:       "address outside the range",
+			address:    net.IPv4(192, 168, 42, 200),
+			wantLeases: map[string]string{},
+		},
+		{
+			name:       "no address",
+			wantLeases: map[string]string{},
+		},
+		{
+			name:       "client moving to another address",
+			prepare:    func(leases *leasePool) { leases.ack(macs[0], first, "") },
+			address:    second,
+			wantOK:     true,
+			wantLeases: map[string]string{macs[0]: "192.168.42.3"},
+		},
+		{
+			name: "released address",
+			prepare: func(leases *leasePool) {
+				leases.ack(macs[1], first, "")
+				leases.release(macs[1], first)
+			},
+			address:    first,
+			wantOK:     true,
+			wantLeases: map[string]string{macs[0]: "192.168.42.2"},
+		},
+		{
+			name: "release by another client ignored",
+			prepare: func(leases *leasePool) {
+				leases.ack(macs[1], first, "")
+				leases.release(macs[0], first)
+			},
+			address:    first,
+			wantLeases: map[string]string{macs[1]: "192.168.42.2"},
+		},
+		{
+			name: "declined address",
+			prepare: func(leases *leasePool) {
+				leases.offer(macs[1], first, "")
+				leases.decline(macs[1], first)
+			},
+			address:    first,
+			wantLeases: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leases := newTestLeasePool(t, 3)
+			if tt.prepare != nil {
+				tt.prepare(leases)
+			}
+			if _, ok := leases.ack(macs[0], tt.address, ""); ok != tt.wantOK {
+				t.Errorf("request acknowledged %t, want %t", ok, tt.wantOK)
+			}
+			got := leases.list()
+			if len(got) != len(tt.wantLeases) {
+				t.Fatalf("leases %v, want %v", got, tt.wantLeases)
+			}
+			for _, current := range got {
+				if tt.wantLeases[current.MAC] != current.IP {
+					t.Errorf("leases %v, want %v", got, tt.wantLeases)
+				}
+			}
+		})
+	}
+}
+
+func TestLeasePoolDecline(t *testing.T) {
+	leases := newTestLeasePool(t, 2)
+	macs := testMACs(2)
+	ip := leases.offer(macs[0], nil, "")
+	leases.decline(macs[0], ip)
+	if next := leases.offer(macs[0], nil, ""); next.String() != "192.168.42.3" {
+		t.Fatalf("offer after decline is %s, want 192.168.42.3", next)
+	}
+	if next := leases.offer(macs[1], ip, ""); next != nil {
+		t.Fatalf("declined address %s offered again", next)
+	}
+	leases.expire(time.Now().Add(leaseDuration + time.Second))
+	if next := leases.offer(macs[1], ip, ""); !next.Equal(ip) {
+		t.Errorf("offer %s once the declined address expired, want %s", next, ip)
+	}
+}
@@ -0,0 +1,88 @@
+package portalserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/umeshlumbhani/go-wifi-connect/internal/models"
+)
+
+// PortalServer serves DHCP and DNS on the portal interface in place of dnsmasq.
+// Every name resolves to the gateway so clients open the portal whatever page they ask for.
+type PortalServer struct {
+	Log      *logrus.Logger
+	Cfg      models.ConfigHandler
+	mu       sync.Mutex
+	dhcpConn *net.UDPConn
+	dnsConn  *net.UDPConn
+	leases   *leasePool
+	wg       sync.WaitGroup
+}
+
+var errAlreadyStarted = errors.New("portal server already started")
+
+// NewPortalServer returns access to this module
+func NewPortalServer(l *logrus.Logger, cfg models.ConfigHandler) *PortalServer {
+	return &PortalServer{
+		Log: l,
+		Cfg: cfg,
+	}
+}
+
+// Start listens for DHCP and DNS requests on the interface, the gateway has to be assigned to it already
+func (p *PortalServer) Start(dInt string, subnet models.PortalSubnet) (err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.dhcpConn != nil {
+		return errAlreadyStarted
+	}
+	leases, err := newLeasePool(subnet)
+	if err != nil {
+		return
+	}
+	dhcpConn, err := listenUDP(dInt, &net.UDPAddr{IP: net.IPv4zero, Port: dhcpServerPort})
+	if err != nil {
+		err = fmt.Errorf("found error on listening for DHCP on %s [%s]", dInt, err.Error())
+		return
+	}
+	dnsConn, err := listenUDP(dInt, &net.UDPAddr{IP: subnet.Gateway, Port: dnsPort})
+	if err != nil {
+		dhcpConn.Close()
+		err = fmt.Errorf("found error on listening for DNS on %s [%s]", dInt, err.Error())
+		return
+	}
+	p.Log.Info(fmt.Sprintf("Start portal server on %s, leasing %s - %s", dInt, subnet.DHCPStart, subnet.DHCPEnd))
+	p.dhcpConn, p.dnsConn, p.leases = dhcpConn, dnsConn, leases
+	p.wg.Add(2)
+	go p.serveDHCP(dhcpConn, subnet, leases)
+	go p.serveDNS(dnsConn, subnet.Gateway)
+	return
+}
+
+// Stop closes the DHCP and DNS sockets, the leases are forgotten
+func (p *PortalServer) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.dhcpConn == nil {
+		return
+	}
+	p.Log.Info("Stop portal server")
+	p.dhcpConn.Close()
+	p.dnsConn.Close()
+	p.wg.Wait()
+	p.dhcpConn, p.dnsConn, p.leases = nil, nil, nil
+}
+
+// GetLeases returns the active leases, ordered by address
+func (p *PortalServer) GetLeases() (leases []models.Lease) {
+	p.mu.Lock()
+	pool := p.leases
+	p.mu.Unlock()
+	if pool == nil {
+		return []models.Lease{}
+	}
+	return pool.list()
+}
@@ -0,0 +1,34 @@
+package portalserver
+
+import (
+	"context"
+	"net"
+	"syscall"
+)
+
+// listenUDP listens on the address for the datagrams received by the interface only, broadcasts included
+func listenUDP(dInt string, addr *net.UDPAddr) (*net.UDPConn, error) {
+	lc := net.ListenConfig{
+		Control: func(network string, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+				if sockErr == nil {
+					sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
+				}
+				if sockErr == nil {
+					sockErr = syscall.BindToDevice(int(fd), dInt)
+				}
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	conn, err := lc.ListenPacket(context.Background(), "udp4", addr.String())
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package portalserver
+
+import (
+	"errors"
+	"net"
+)
+
+// listenUDP needs SO_BINDTODEVICE, the portal server only runs on Linux like NetworkManager
+func listenUDP(dInt string, addr *net.UDPAddr) (*net.UDPConn, error) {
+	return nil, errors.New("binding to an interface is only supported on Linux")
+}
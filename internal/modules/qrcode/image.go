@@ -0,0 +1,56 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// quietZone is the light border around the symbol required by the specification, in modules
+const quietZone = 4
+
+// PNG returns the code as a black and white PNG image, every module being scale pixels wide
+func (c *Code) PNG(scale int) ([]byte, error) {
+	width := (c.Size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Dark(x, y) {
+				continue
+			}
+			for py := (y + quietZone) * scale; py < (y+quietZone+1)*scale; py++ {
+				for px := (x + quietZone) * scale; px < (x+quietZone+1)*scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG returns the code as an SVG image, every module being scale user units wide.
+// The dark modules form a single path so viewers do not show seams between them.
+func (c *Code) SVG(scale int) []byte {
+	width := c.Size + 2*quietZone
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" viewBox="0 0 %d %d" width="%d" height="%d" shape-rendering="crispEdges">`,
+		width, width, width*scale, width*scale)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/><path fill="#000000" d="`, width, width)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				fmt.Fprintf(&buf, "M%d,%dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,473 @@
+package qrcode
+
+import (
+	"errors"
+)
+
+// ErrDataTooLong is returned when the data does not fit in the largest QR code
+var ErrDataTooLong = errors.New("data too long for a QR code")
+
+// Code is a QR code encoded in byte mode with the medium error correction level,
+// which stays readable with up to 15% of the symbol damaged or badly displayed
+type Code struct {
+	// Version ranges from 1 to 40, Size is the width of the symbol in modules
+	Version int
+	Size    int
+	modules [][]bool
+	// isFunction marks the finder, timing, alignment, format and version modules
+	isFunction [][]bool
+}
+
+const (
+	minVersion = 1
+	maxVersion = 40
+	// formatLevelM is the format information value of the medium error correction level
+	formatLevelM = 0
+)
+
+// eccCodewordsPerBlock and eccBlocks give the error correction layout of every version at the medium level
+var eccCodewordsPerBlock = [maxVersion + 1]int{-1,
+	10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26,
+	26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28}
+
+var eccBlocks = [maxVersion + 1]int{-1,
+	1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16,
+	17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49}
+
+// Encode returns the smallest QR code holding the data
+func Encode(data []byte) (*Code, error) {
+	version := minVersion
+	for ; version <= maxVersion; version++ {
+		if dataBits(version, len(data)) <= dataCodewords(version)*8 {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, ErrDataTooLong
+	}
+	c := newCode(version, data)
+	c.applyBestMask()
+	return c, nil
+}
+
+// newCode returns the symbol of the version holding the data, before any mask is applied
+func newCode(version int, data []byte) *Code {
+	c := &Code{Version: version, Size: version*4 + 17}
+	c.modules = newGrid(c.Size)
+	c.isFunction = newGrid(c.Size)
+	c.drawFunctionPatterns()
+	c.drawCodewords(c.addErrorCorrection(encodeData(version, data)))
+	return c
+}
+
+// Dark tells whether the module at column x and row y is dark
+func (c *Code) Dark(x int, y int) bool {
+	return c.modules[y][x]
+}
+
+func newGrid(size int) [][]bool {
+	grid := make([][]bool, size)
+	for i := range grid {
+		grid[i] = make([]bool, size)
+	}
+	return grid
+}
+
+// countBits returns the length of the character count indicator of byte mode
+func countBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// dataBits returns the length of the segment holding n bytes
+func dataBits(version int, n int) int {
+	if n >= 1<<uint(countBits(version)) {
+		return 1 << 30
+	}
+	return 4 + countBits(version) + n*8
+}
+
+// rawCodewords returns the number of codewords of a version, data and error correction
+func rawCodewords(version int) int {
+	modules := (16*version+128)*version + 64
+	if version >= 2 {
+		alignments := version/7 + 2
+		modules -= (25*alignments-10)*alignments - 55
+		if version >= 7 {
+			modules -= 36
+		}
+	}
+	return modules / 8
+}
+
+func dataCodewords(version int) int {
+	return rawCodewords(version) - eccCodewordsPerBlock[version]*eccBlocks[version]
+}
+
+// bitBuffer accumulates the bits of the data codewords, most significant bit first
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value int, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>uint(i))&1 != 0)
+	}
+}
+
+// encodeData returns the data codewords: the byte mode segment, its terminator and the padding
+func encodeData(version int, data []byte) []byte {
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := dataCodewords(version) * 8
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.append(0, terminator)
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+	return codewords
+}
+
+// addErrorCorrection splits the data in blocks, appends their error correction codewords and interleaves them
+func (c *Code) addErrorCorrection(data []byte) []byte {
+	blocks := eccBlocks[c.Version]
+	eccLen := eccCodewordsPerBlock[c.Version]
+	raw := rawCodewords(c.Version)
+	shortBlocks := blocks - raw%blocks
+	shortLen := raw / blocks
+	divisor := reedSolomonDivisor(eccLen)
+
+	var all [][]byte
+	for i, k := 0, 0; i < blocks; i++ {
+		n := shortLen - eccLen
+		if i >= shortBlocks {
+			n++
+		}
+		block := append([]byte{}, data[k:k+n]...)
+		k += n
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < shortBlocks {
+			// placeholder keeping every block the same length, skipped when interleaving
+			block = append(block, 0)
+		}
+		all = append(all, append(block, ecc...))
+	}
+	var result []byte
+	for i := 0; i <= shortLen; i++ {
+		for j, block := range all {
+			if i != shortLen-eccLen || j >= shortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x byte, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		carry := z >> 7
+		z = z<<1 ^ carry*0x1D
+		z ^= (y >> uint(i) & 1) * x
+	}
+	return z
+}
+
+// reedSolomonDivisor returns the generator polynomial of the degree, leading coefficient left out
+func reedSolomonDivisor(degree int) []byte {
+	divisor := make([]byte, degree)
+	divisor[degree-1] = 1
+	var root byte = 1
+	for i := 0; i < degree; i++ {
+		for j := range divisor {
+			divisor[j] = gfMultiply(divisor[j], root)
+			if j+1 < len(divisor) {
+				divisor[j] ^= divisor[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return divisor
+}
+
+// reedSolomonRemainder returns the error correction codewords of the data
+func reedSolomonRemainder(data []byte, divisor []byte) []byte {
+	remainder := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ remainder[0]
+		copy(remainder, remainder[1:])
+		remainder[len(remainder)-1] = 0
+		for i := range remainder {
+			remainder[i] ^= gfMultiply(divisor[i], factor)
+		}
+	}
+	return remainder
+}
+
+func (c *Code) setFunction(x int, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns draws everything but the data, the format bits are reserved until the mask is known
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.Size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.Size-4, 3)
+	c.drawFinderPattern(3, c.Size-4)
+
+	positions := alignmentPositions(c.Version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// the corners taken by the finder patterns
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			c.drawAlignmentPattern(x, y)
+		}
+	}
+	c.drawFormatBits(0)
+	c.drawVersion()
+}
+
+// drawFinderPattern draws a finder pattern and its separator around the center
+func (c *Code) drawFinderPattern(x int, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			px, py := x+dx, y+dy
+			if px < 0 || px >= c.Size || py < 0 || py >= c.Size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			c.setFunction(px, py, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignmentPattern(x int, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions returns the row and column centers of the alignment patterns
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	count := version/7 + 2
+	step := (version*8 + count*3 + 5) / (count*4 - 4) * 2
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, pos := count-1, version*4+17-7; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// drawFormatBits draws both copies of the error correction level and mask, protected by a BCH code
+func (c *Code) drawFormatBits(mask int) {
+	data := formatLevelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return bits>>uint(i)&1 != 0 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(i))
+	}
+	// the dark module
+	c.setFunction(8, c.Size-8, true)
+}
+
+// drawVersion draws both copies of the version, from version 7 on
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	rem := c.Version
+	for i := 0; i < 12; i++ {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := c.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := bits>>uint(i)&1 != 0
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at a time from the bottom right
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// the vertical timing pattern
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.isFunction[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = codewords[i/8]>>uint(7-i%8)&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+// masks are the data mask patterns, a module is inverted where the condition holds
+var masks = [8]func(x int, y int) bool{
+	func(x, y int) bool { return (x+y)%2 == 0 },
+	func(x, y int) bool { return y%2 == 0 },
+	func(x, y int) bool { return x%3 == 0 },
+	func(x, y int) bool { return (x+y)%3 == 0 },
+	func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+	func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+	func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+	func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.isFunction[y][x] && masks[mask](x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask applies the mask with the lowest penalty, masks are their own inverse
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := range masks {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+}
+
+// finderLike is the 1:1:3:1:1 ratio of the finder patterns, with 4 light modules on one side
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores the symbol with the rules of the specification, lower is easier to read
+func (c *Code) penalty() (penalty int) {
+	dark := 0
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < c.Size && y+1 < c.Size {
+				color := c.modules[y][x]
+				if c.modules[y][x+1] == color && c.modules[y+1][x] == color && c.modules[y+1][x+1] == color {
+					penalty += 3
+				}
+			}
+		}
+	}
+	for i := 0; i < c.Size; i++ {
+		row := func(j int) bool { return c.modules[i][j] }
+		column := func(j int) bool { return c.modules[j][i] }
+		penalty += c.linePenalty(row) + c.linePenalty(column)
+	}
+	total := c.Size * c.Size
+	penalty += abs(dark*100/total-50) / 5 * 10
+	return
+}
+
+// linePenalty scores the runs of one color and the finder-like patterns of a row or column
+func (c *Code) linePenalty(module func(int) bool) (penalty int) {
+	run := 1
+	for j := 1; j <= c.Size; j++ {
+		if j < c.Size && module(j) == module(j-1) {
+			run++
+			continue
+		}
+		if run >= 5 {
+			penalty += run - 2
+		}
+		run = 1
+	}
+	for j := 0; j+11 <= c.Size; j++ {
+		for _, pattern := range finderLike {
+			matches := true
+			for k, dark := range pattern {
+				if module(j+k) != dark {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				penalty += 40
+			}
+		}
+	}
+	return
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,161 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// goldenCodes are the symbols of testdata, one per mask, produced by github.com/skip2/go-qrcode at the medium level.
+// That encoder departs from the penalty rules of the specification, bestMask is the mask with the lowest penalty under them.
+var goldenCodes = []struct {
+	version  int
+	data     string
+	bestMask int
+}{
+	{version: 1, data: "wifi_connect", bestMask: 2},
+	{version: 2, data: "wifi_connect_setup_portal", bestMask: 6},
+	{version: 7, data: strings.Repeat("wifi_connect_", 9), bestMask: 2},
+	// the first version with a 16 bit character count
+	{version: 10, data: strings.Repeat("portal_", 30), bestMask: 2},
+}
+
+// readGolden returns the symbol of testdata, one row per line with '#' for the dark modules
+func readGolden(t *testing.T, version int, mask int) []byte {
+	t.Helper()
+	golden, err := os.ReadFile(filepath.Join("testdata", fmt.Sprintf("version%d-mask%d.txt", version, mask)))
+	if err != nil {
+		t.Fatalf("reading the golden symbol: %s", err.Error())
+	}
+	return golden
+}
+
+func (c *Code) String() string {
+	var b strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func TestGoldenMasks(t *testing.T) {
+	for _, tt := range goldenCodes {
+		for mask := range masks {
+			t.Run(fmt.Sprintf("version %d mask %d", tt.version, mask), func(t *testing.T) {
+				c := newCode(tt.version, []byte(tt.data))
+				c.applyMask(mask)
+				c.drawFormatBits(mask)
+				if got, want := c.String(), readGolden(t, tt.version, mask); !bytes.Equal([]byte(got), want) {
+					t.Errorf("symbol\n%s\nwant\n%s", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, tt := range goldenCodes {
+		t.Run(fmt.Sprintf("version %d", tt.version), func(t *testing.T) {
+			c, err := Encode([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("Encode: %s", err.Error())
+			}
+			if c.Version != tt.version || c.Size != tt.version*4+17 {
+				t.Fatalf("version %d size %d, want version %d", c.Version, c.Size, tt.version)
+			}
+			if got, want := c.String(), readGolden(t, tt.version, tt.bestMask); !bytes.Equal([]byte(got), want) {
+				t.Errorf("symbol\n%s\nwant mask %d\n%s", got, tt.bestMask, want)
+			}
+		})
+	}
+}
+
+func TestEncodeCapacity(t *testing.T) {
+	tests := []struct {
+		name        string
+		length      int
+		wantVersion int
+		wantErr     error
+	}{
+		{name: "empty", length: 0, wantVersion: 1},
+		{name: "version 1 full", length: 14, wantVersion: 1},
+		{name: "version 2", length: 15, wantVersion: 2},
+		{name: "version 9 full", length: 180, wantVersion: 9},
+		{name: "version 10", length: 181, wantVersion: 10},
+		{name: "version 40 full", length: 2331, wantVersion: 40},
+		{name: "too long", length: 2332, wantErr: ErrDataTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode(bytes.Repeat([]byte{'a'}, tt.length))
+			if err != tt.wantErr {
+				t.Fatalf("Encode returned error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && c.Version != tt.wantVersion {
+				t.Errorf("version %d, want %d", c.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestReedSolomonRemainder(t *testing.T) {
+	// the data codewords of HELLO WORLD in a version 1 symbol at the medium level
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(len(want))); !bytes.Equal(got, want) {
+		t.Errorf("error correction %v, want %v", got, want)
+	}
+}
+
+func TestAlignmentPositions(t *testing.T) {
+	tests := []struct {
+		version int
+		want    []int
+	}{
+		{version: 1},
+		{version: 2, want: []int{6, 18}},
+		{version: 7, want: []int{6, 22, 38}},
+		{version: 32, want: []int{6, 34, 60, 86, 112, 138}},
+		{version: 40, want: []int{6, 30, 58, 86, 114, 142, 170}},
+	}
+	for _, tt := range tests {
+		if got := alignmentPositions(tt.version); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("alignmentPositions(%d) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestLinePenalty(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want int
+	}{
+		{name: "alternating", line: "#.#.#.#.#.#.#.#.#.#.#", want: 0},
+		{name: "run of 4", line: "####.#.#.#.#.#.#.#.#.", want: 0},
+		{name: "run of 5", line: ".....#.#.#.#.#.#.#.#.", want: 3},
+		{name: "run of 7", line: "#######.#.#.#.#.#.#.#", want: 5},
+		{name: "runs at both ends", line: "######.#.#.#.#.######", want: 8},
+		{name: "finder-like with light modules after", line: "#.###.#....#.#.#.#.#.", want: 40},
+		{name: "finder-like with light modules before", line: ".#.#.#.#.#....#.###.#", want: 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Code{Size: len(tt.line)}
+			if got := c.linePenalty(func(j int) bool { return tt.line[j] == '#' }); got != tt.want {
+				t.Errorf("penalty of %s is %d, want %d", tt.line, got, tt.want)
+			}
+		})
+	}
+}